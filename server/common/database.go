@@ -25,7 +25,7 @@ type User struct {
 // Survey 问卷结构体
 type Survey struct {
 	SurveyID          string    `gorm:"column:SurveyID;primaryKey;size:36"` // 问卷ID
-	AccessID          string    `gorm:"column:AccessID"`                    // 访问ID
+	AccessID          string    `gorm:"column:AccessID;size:64;unique"`     // 访问ID
 	UserID            string    `gorm:"column:UserID;size:36"`              // 用户ID
 	Title             string    `gorm:"column:Title"`                       // 问卷标题
 	Description       string    `gorm:"column:Description"`                 // 问卷描述
@@ -56,6 +56,111 @@ type Survey struct {
 	ShowContent       string    `gorm:"column:ShowContent"`                 // 显示内容
 	QuestionIDs       string    `gorm:"column:QuestionIDsList"`             // 问卷中的问题列表
 	ResponseIDs       []string  `gorm:"type:json"`                          // 问卷的响应列表
+
+	LastUpdateUser   string         `gorm:"column:LastUpdateUser;size:36"`     // 最后更新用户ID
+	Revision         int            `gorm:"column:Revision"`                   // 修订号，用于编辑并发控制
+	CurrentVersion   int            `gorm:"column:CurrentVersion"`             // 当前版本号
+	PublishedVersion int            `gorm:"column:PublishedVersion"`           // 已发布的版本号
+	DraftContent     string         `gorm:"column:DraftContent;type:longtext"` // 未发布的草稿（SurveyModel JSON），为空表示没有草稿
+	FolderID         string         `gorm:"column:FolderID;size:36;index"`     // 所在文件夹ID，为空表示根目录
+	DeletedAt        gorm.DeletedAt `gorm:"column:DeletedAt;index"`            // 移入回收站时间
+
+	RandomizeQuestions bool `gorm:"column:RandomizeQuestions"` // 是否按答题者随机打乱每页内的题目顺序
+	QuizMode           bool `gorm:"column:QuizMode"`           // 是否为测验模式，提交后自动评分
+	ShowScore          bool `gorm:"column:ShowScore"`          // 测验模式下是否在提交后显示得分
+
+	Variables string `gorm:"column:Variables;type:text"` // 计算变量定义列表（JSON），提交时按表达式计算
+
+	DefaultLanguage string `gorm:"column:DefaultLanguage;size:16"` // 默认语言，问卷原文使用的语言
+	Languages       string `gorm:"column:Languages"`               // 其他语言列表，以逗号分隔
+}
+
+// SurveyVersion 问卷版本快照结构体
+type SurveyVersion struct {
+	ID         uint      `gorm:"column:ID;primaryKey;autoIncrement"`                     // 记录ID
+	SurveyID   string    `gorm:"column:SurveyID;size:36;uniqueIndex:idx_survey_version"` // 问卷ID
+	Version    int       `gorm:"column:Version;uniqueIndex:idx_survey_version"`          // 版本号
+	Snapshot   string    `gorm:"column:Snapshot;type:longtext"`                          // SurveyModel JSON 快照
+	UserID     string    `gorm:"column:UserID;size:36"`                                  // 保存者ID
+	Note       string    `gorm:"column:Note"`                                            // 版本说明
+	CreateTime time.Time `gorm:"column:CreateTime"`                                      // 保存时间
+}
+
+// SurveyQuota 问卷配额结构体，满足条件的答卷数达到上限后执行配额动作
+type SurveyQuota struct {
+	QuotaID     string    `gorm:"column:QuotaID;primaryKey;size:36"` // 配额ID
+	SurveyID    string    `gorm:"column:SurveyID;size:36;index"`     // 问卷ID
+	Name        string    `gorm:"column:Name;size:100"`              // 配额名称
+	Conditions  string    `gorm:"column:Conditions;type:text"`       // 条件列表（JSON），所有条件同时满足时计入配额
+	Cap         int       `gorm:"column:Cap"`                        // 上限
+	Count       int       `gorm:"column:Count"`                      // 已计入的答卷数
+	Action      string    `gorm:"column:Action;size:20"`             // 配额已满时的动作：reject / end / redirect
+	Message     string    `gorm:"column:Message"`                    // 配额已满时的提示
+	RedirectURL string    `gorm:"column:RedirectURL"`                // 配额已满时的跳转地址
+	CreateTime  time.Time `gorm:"column:CreateTime"`                 // 创建时间
+}
+
+// SurveyTranslation 问卷文本的译文，Key 标识被翻译的字段，如 question.<QuestionID>.title
+type SurveyTranslation struct {
+	SurveyID string `gorm:"column:SurveyID;primaryKey;size:36"`        // 问卷ID
+	Language string `gorm:"column:Language;primaryKey;size:16"`        // 语言
+	Key      string `gorm:"column:TranslationKey;primaryKey;size:100"` // 字段标识
+	Text     string `gorm:"column:Text;type:text"`                     // 译文
+}
+
+// Folder 问卷文件夹结构体
+type Folder struct {
+	FolderID   string    `gorm:"column:FolderID;primaryKey;size:36"` // 文件夹ID
+	UserID     string    `gorm:"column:UserID;size:36;index"`        // 用户ID
+	ParentID   string    `gorm:"column:ParentID;size:36;index"`      // 父文件夹ID，为空表示根目录
+	Name       string    `gorm:"column:Name;size:100"`               // 文件夹名称
+	CreateTime time.Time `gorm:"column:CreateTime"`                  // 创建时间
+}
+
+// SurveyTag 问卷标签结构体
+type SurveyTag struct {
+	SurveyID string `gorm:"column:SurveyID;primaryKey;size:36"`  // 问卷ID
+	Tag      string `gorm:"column:Tag;primaryKey;size:50;index"` // 标签
+	UserID   string `gorm:"column:UserID;size:36;index"`         // 用户ID
+}
+
+// SurveyTemplate 问卷模板结构体，模板题目以 TemplateID 作为 SurveyID 存储在问题相关表中
+type SurveyTemplate struct {
+	TemplateID    string    `gorm:"column:TemplateID;primaryKey;size:64"` // 模板ID
+	UserID        string    `gorm:"column:UserID;size:36;index"`          // 创建者ID，内置模板为空
+	Name          string    `gorm:"column:Name"`                          // 模板名称
+	Description   string    `gorm:"column:Description"`                   // 模板描述
+	Category      string    `gorm:"column:Category;size:50;index"`        // 模板分类
+	Shared        bool      `gorm:"column:Shared"`                        // 是否对全站共享
+	BuiltIn       bool      `gorm:"column:BuiltIn"`                       // 是否为内置模板
+	QuestionCount int       `gorm:"column:QuestionCount"`                 // 题目数量
+	QuestionIDs   string    `gorm:"column:QuestionIDsList"`               // 模板中的问题列表
+	CreateTime    time.Time `gorm:"column:CreateTime"`                    // 创建时间
+
+	RandomizeQuestions bool   `gorm:"column:RandomizeQuestions"`  // 是否随机打乱每页内的题目顺序
+	QuizMode           bool   `gorm:"column:QuizMode"`            // 是否为测验模式
+	ShowScore          bool   `gorm:"column:ShowScore"`           // 是否在提交后显示得分
+	Variables          string `gorm:"column:Variables;type:text"` // 计算变量定义列表（JSON）
+
+	DefaultLanguage string `gorm:"column:DefaultLanguage;size:16"` // 默认语言，译文以 TemplateID 作为 SurveyID 存储
+	Languages       string `gorm:"column:Languages"`               // 其他语言列表，以逗号分隔
+}
+
+// BankQuestion 题库题目结构体，题目内容以 BankQuestionID 作为 SurveyID 存储在问题相关表中
+type BankQuestion struct {
+	BankQuestionID string    `gorm:"column:BankQuestionID;primaryKey;size:36"` // 题库题目ID
+	UserID         string    `gorm:"column:UserID;size:36;index"`              // 所属用户ID
+	QuestionID     string    `gorm:"column:QuestionID;size:36"`                // 存储的题目ID
+	Title          string    `gorm:"column:Title"`                             // 题目标题，用于搜索
+	QuestionType   string    `gorm:"column:QuestionType"`                      // 题目类型
+	CreateTime     time.Time `gorm:"column:CreateTime"`                        // 创建时间
+}
+
+// BankQuestionTag 题库题目标签结构体
+type BankQuestionTag struct {
+	BankQuestionID string `gorm:"column:BankQuestionID;primaryKey;size:36"` // 题库题目ID
+	Tag            string `gorm:"column:Tag;primaryKey;size:50;index"`      // 标签
+	UserID         string `gorm:"column:UserID;size:36;index"`              // 用户ID
 }
 
 // Question 问题结构体
@@ -71,6 +176,18 @@ type Question struct {
 	OptionIDs     string `gorm:"column:OptionIDs"`             // 问题选项列表
 	TextFillInIDs string `gorm:"column:TextFillInIDs"`         // 文本填空框
 	NumFillInIDs  string `gorm:"column:NumFillInIDs"`          // 数字填空类型
+
+	Required         bool    `gorm:"column:Required"`                     // 是否必答
+	RequiredMessage  string  `gorm:"column:RequiredMessage"`              // 必答未填时的提示
+	BankQuestionID   string  `gorm:"column:BankQuestionID;size:36;index"` // 来源题库题目ID
+	RandomizeOptions bool    `gorm:"column:RandomizeOptions"`             // 是否随机打乱选项顺序
+	PageBreak        bool    `gorm:"column:PageBreak"`                    // 是否在此题之前分页
+	Points           float64 `gorm:"column:Points"`                       // 测验模式下本题分值
+	PartialCredit    bool    `gorm:"column:PartialCredit"`                // 是否按答对比例给分
+
+	DisplayCondition string `gorm:"column:DisplayCondition;type:text"` // 显示条件表达式，为空时总是显示
+
+	Image string `gorm:"column:Image"` // 题目图片地址
 }
 
 // QuestionOption 问题选项结构体
@@ -79,18 +196,60 @@ type QuestionOption struct {
 	QuestionID    string `gorm:"column:QuestionID;index"`    // 问题ID
 	SurveyID      string `gorm:"column:SurveyID;index"`      // 问卷ID
 	OptionContent string `gorm:"column:OptionContent"`       // 选项内容
+
+	PinLast     bool `gorm:"column:PinLast"`     // 随机排序时固定在末尾，如“其他”“以上都不是”
+	IsOpenEnded bool `gorm:"column:IsOpenEnded"` // 是否为“其他，请注明”选项，选中时需填写文本
+	IsExclusive bool `gorm:"column:IsExclusive"` // 是否为互斥选项，如“以上都不是”，不能与其他选项同时选中
+	IsCorrect   bool `gorm:"column:IsCorrect"`   // 测验模式下是否为正确选项
+
+	OptionValue *float64 `gorm:"column:OptionValue"` // 选项分值（编码），用于统计分析和计算变量，为空时取选项位置（从 1 开始）
+
+	Image string `gorm:"column:Image"` // 选项图片地址
 }
 
 type QuestionTextFillIn struct {
 	TextFillInID string `gorm:"column:TextFillInID;primaryKey"` // 文本填空ID
 	QuestionID   string `gorm:"column:QuestionID;index"`        // 问题ID
 	SurveyID     string `gorm:"column:SurveyID;index"`          // 问卷ID
+
+	MinLength    int    `gorm:"column:MinLength"`    // 最少字数，0 表示不限
+	MaxLength    int    `gorm:"column:MaxLength"`    // 最多字数，0 表示不限
+	Pattern      string `gorm:"column:Pattern"`      // 内容需匹配的正则表达式
+	ErrorMessage string `gorm:"column:ErrorMessage"` // 校验失败时的提示
+
+	AcceptedAnswers []string `gorm:"column:AcceptedAnswers;serializer:json"` // 测验模式下可接受的答案，忽略大小写和首尾空白
 }
 
 type QuestionNumFillIn struct {
 	NumFillInID string `gorm:"column:NumFillInID;primaryKey"` // 数字填空ID
 	QuestionID  string `gorm:"column:QuestionID;index"`       // 问题ID
 	SurveyID    string `gorm:"column:SurveyID;index"`         // 问卷ID
+
+	MinValue     *float64 `gorm:"column:MinValue"`     // 最小值，为空表示不限
+	MaxValue     *float64 `gorm:"column:MaxValue"`     // 最大值，为空表示不限
+	IntegerOnly  bool     `gorm:"column:IntegerOnly"`  // 是否只允许整数
+	ErrorMessage string   `gorm:"column:ErrorMessage"` // 校验失败时的提示
+}
+
+// UploadedFile 上传文件结构体，文件内容保存在存储中，不再被引用的文件会被定期清理
+type UploadedFile struct {
+	FileID      string    `gorm:"column:FileID;primaryKey;size:36"` // 文件ID
+	UserID      string    `gorm:"column:UserID;size:36;index"`      // 上传者ID
+	Name        string    `gorm:"column:Name"`                      // 原始文件名
+	ContentType string    `gorm:"column:ContentType;size:100"`      // 文件类型
+	Size        int64     `gorm:"column:Size"`                      // 文件大小（字节）
+	StorageKey  string    `gorm:"column:StorageKey"`                // 存储中的路径
+	CreateTime  time.Time `gorm:"column:CreateTime;index"`          // 上传时间
+}
+
+// SurveyStatusHistory 问卷状态变更记录
+type SurveyStatusHistory struct {
+	ID         uint      `gorm:"column:ID;primaryKey;autoIncrement"` // 记录ID
+	SurveyID   string    `gorm:"column:SurveyID;size:36;index"`      // 问卷ID
+	FromStatus string    `gorm:"column:FromStatus"`                  // 原状态
+	ToStatus   string    `gorm:"column:ToStatus"`                    // 新状态
+	UserID     string    `gorm:"column:UserID;size:36"`              // 操作用户ID
+	ChangedAt  time.Time `gorm:"column:ChangedAt"`                   // 变更时间
 }
 
 ///====================================================///=============================///===========================================================================
@@ -103,6 +262,8 @@ type ResponseOption struct {
 	SurveyID      string `gorm:"column:SurveyID;index"`        // 问卷ID
 	OptionContent string `gorm:"column:OptionContent"`         // 选项内容
 	IsSelect      bool   `gorm:"column:IsSelect"`              // 选项内容
+
+	OtherText string `gorm:"column:OtherText;type:text"` // “其他”选项附带的文本
 }
 
 // TextFillIn 文本填空结构体
@@ -116,11 +277,11 @@ type ResponseTextFillIn struct {
 
 // NumFillIn 数字填空结构体
 type ResponseNumFillIn struct {
-	ResponseID  string `gorm:"column:ResponseID;primaryKey"`  // 联合主键之一
-	NumFillInID string `gorm:"column:NumFillInID;primaryKey"` // 数字填空ID
-	QuestionID  string `gorm:"column:QuestionID;index"`       // 问题ID
-	SurveyID    string `gorm:"column:SurveyID;index"`         // 问卷ID
-	NumContent  int    `gorm:"column:NumContent"`             // 数字内容
+	ResponseID  string  `gorm:"column:ResponseID;primaryKey"`  // 联合主键之一
+	NumFillInID string  `gorm:"column:NumFillInID;primaryKey"` // 数字填空ID
+	QuestionID  string  `gorm:"column:QuestionID;index"`       // 问题ID
+	SurveyID    string  `gorm:"column:SurveyID;index"`         // 问卷ID
+	NumContent  float64 `gorm:"column:NumContent"`             // 数字内容
 }
 
 // QuestionResponse 问题结构体
@@ -141,6 +302,25 @@ type SurveyResponse struct {
 	IP         string `gorm:"column:IP"`                    // IP地址
 	IsStar     bool   `gorm:"column:IsStar"`                // 是否加星
 	IsInvalid  bool   `gorm:"column:IsInvalid"`             // 是否无效
+
+	SurveyVersion     int    `gorm:"column:SurveyVersion"`                   // 作答时的问卷版本号
+	Seed              string `gorm:"column:Seed;size:64"`                    // 答题者的随机种子
+	PresentationOrder string `gorm:"column:PresentationOrder;type:longtext"` // 展示给答题者的题目和选项顺序（JSON）
+	RenderedText      string `gorm:"column:RenderedText;type:longtext"`      // 替换占位符后答题者看到的题目文本（JSON）
+
+	Score          *float64 `gorm:"column:Score"`                        // 测验得分，非测验模式为空
+	MaxScore       float64  `gorm:"column:MaxScore"`                     // 测验满分
+	QuestionScores string   `gorm:"column:QuestionScores;type:longtext"` // 各题得分（JSON）
+
+	Language string `gorm:"column:Language;size:16"` // 答题时使用的语言
+}
+
+// ResponseVariable 答卷的计算变量值
+type ResponseVariable struct {
+	ResponseID string  `gorm:"column:ResponseID;primaryKey"`   // 答卷ID
+	Name       string  `gorm:"column:Name;primaryKey;size:50"` // 变量名
+	SurveyID   string  `gorm:"column:SurveyID;size:36;index"`  // 问卷ID
+	Value      float64 `gorm:"column:Value"`                   // 计算结果
 }
 
 // EmailVerification 邮箱验证码结构体
@@ -164,23 +344,37 @@ func InitDb() {
 	if err != nil {
 		panic("failed to connect database: " + err.Error())
 	}
+}
 
+// MigrateDb 自动迁移所有表结构，须在 InitDb 之后调用
+func MigrateDb() {
 	// 打印日志，确认自动迁移开始
 	fmt.Println("Starting AutoMigrate...")
 	// 自动迁移所有表结构
-	err = DB.AutoMigrate(
-		&User{},               // 用户表
-		&Survey{},             // 问卷表
-		&Question{},           // 问题表
-		&QuestionOption{},     // 问题选项表
-		&QuestionTextFillIn{}, // 文本填空表
-		&QuestionNumFillIn{},  // 数字填空表
-		&ResponseOption{},     // 答卷选项表
-		&ResponseTextFillIn{}, // 文本填空答卷表
-		&ResponseNumFillIn{},  // 数字填空答卷表
-		&QuestionResponse{},   // 问题答卷表
-		&SurveyResponse{},     // 问卷答卷表
-		&EmailVerification{},  // 邮箱验证表
+	err := DB.AutoMigrate(
+		&User{},                // 用户表
+		&Survey{},              // 问卷表
+		&Question{},            // 问题表
+		&QuestionOption{},      // 问题选项表
+		&QuestionTextFillIn{},  // 文本填空表
+		&QuestionNumFillIn{},   // 数字填空表
+		&ResponseOption{},      // 答卷选项表
+		&ResponseTextFillIn{},  // 文本填空答卷表
+		&ResponseNumFillIn{},   // 数字填空答卷表
+		&QuestionResponse{},    // 问题答卷表
+		&SurveyResponse{},      // 问卷答卷表
+		&EmailVerification{},   // 邮箱验证表
+		&SurveyStatusHistory{}, // 问卷状态历史表
+		&Folder{},              // 文件夹表
+		&SurveyTag{},           // 问卷标签表
+		&SurveyTemplate{},      // 问卷模板表
+		&SurveyVersion{},       // 问卷版本表
+		&BankQuestion{},        // 题库题目表
+		&BankQuestionTag{},     // 题库题目标签表
+		&SurveyQuota{},         // 问卷配额表
+		&ResponseVariable{},    // 答卷计算变量表
+		&SurveyTranslation{},   // 问卷译文表
+		&UploadedFile{},        // 上传文件表
 	)
 	if err != nil {
 		panic("failed to migrate database: " + err.Error())
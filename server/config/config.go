@@ -30,6 +30,27 @@ type AppConfig struct {
 		TokenExpiry string `mapstructure:"token_expiry"`
 	} `mapstructure:"auth"`
 
+	Survey struct {
+		TrashRetention string `mapstructure:"trash_retention"`
+		PurgeInterval  string `mapstructure:"purge_interval"`
+	} `mapstructure:"survey"`
+
+	Upload struct {
+		Storage    string `mapstructure:"storage"`     // 存储方式：local / s3
+		Dir        string `mapstructure:"dir"`         // 本地存储目录
+		MaxSize    int64  `mapstructure:"max_size"`    // 单个文件大小上限（字节）
+		GCGrace    string `mapstructure:"gc_grace"`    // 未被引用的文件保留时长，过期后清理
+		GCInterval string `mapstructure:"gc_interval"` // 文件清理任务执行间隔
+		S3         struct {
+			Endpoint  string `mapstructure:"endpoint"`
+			Region    string `mapstructure:"region"`
+			Bucket    string `mapstructure:"bucket"`
+			AccessKey string `mapstructure:"access_key"`
+			SecretKey string `mapstructure:"secret_key"`
+			PathStyle bool   `mapstructure:"path_style"`
+		} `mapstructure:"s3"`
+	} `mapstructure:"upload"`
+
 	SMTP struct {
 		From     string `mapstructure:"from"`
 		Password string `mapstructure:"password"`
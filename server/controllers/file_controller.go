@@ -1 +1,77 @@
 package controllers
+
+import (
+	"errors"
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadFile 上传图片，返回可在问卷外观、题目和选项中引用的文件地址
+func UploadFile(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// 限制请求体大小，为 multipart 的边界和其他字段预留空间
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxUploadSize()+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, services.ErrFileTooLarge.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	file, err := services.UploadFile(userID, header)
+	switch {
+	case errors.Is(err, services.ErrFileTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, services.ErrFileTypeNotAllowed):
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "File uploaded successfully", gin.H{
+		"file": file,
+	})
+}
+
+// GetFile 返回上传的文件，文件内容不会改变，可长期缓存
+func GetFile(c *gin.Context) {
+	fileID := c.Param("fileId")
+	etag := `"` + fileID + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	file, content, err := services.OpenFile(fileID)
+	if errors.Is(err, services.ErrFileNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, content, map[string]string{
+		"ETag":                   etag,
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
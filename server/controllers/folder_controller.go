@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFolders 获取用户的文件夹列表
+func ListFolders(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	folders, err := services.ListFolders(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Folders retrieved successfully", gin.H{
+		"data": folders,
+	})
+}
+
+// CreateFolder 创建文件夹
+func CreateFolder(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		Name     string `json:"name" binding:"required"`
+		ParentID string `json:"parentId"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	folder, err := services.CreateFolder(userID, request.Name, request.ParentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Folder created successfully", gin.H{
+		"folder": folder,
+	})
+}
+
+// RenameFolder 重命名文件夹
+func RenameFolder(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		FolderID string `json:"folderId" binding:"required"`
+		Name     string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.RenameFolder(userID, request.FolderID, request.Name); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Folder renamed successfully", nil)
+}
+
+// MoveFolder 移动文件夹
+func MoveFolder(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		FolderID string `json:"folderId" binding:"required"`
+		ParentID string `json:"parentId"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.MoveFolder(userID, request.FolderID, request.ParentID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Folder moved successfully", nil)
+}
+
+// DeleteFolder 删除文件夹，其中的问卷移动到根目录
+func DeleteFolder(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		FolderID string `json:"folderId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.DeleteFolder(userID, request.FolderID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Folder deleted successfully", nil)
+}
+
+// MoveSurveys 批量移动问卷到文件夹
+func MoveSurveys(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyIDs []string `json:"surveyIds" binding:"required"`
+		FolderID  string   `json:"folderId"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.MoveSurveys(userID, request.SurveyIDs, request.FolderID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Surveys moved successfully", nil)
+}
+
+// ListTags 获取用户使用过的标签
+func ListTags(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tags, err := services.ListTags(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tags retrieved successfully", gin.H{
+		"data": tags,
+	})
+}
+
+// TagSurvey 为问卷添加标签
+func TagSurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string   `json:"surveyId" binding:"required"`
+		Tags     []string `json:"tags" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.TagSurvey(userID, request.SurveyID, request.Tags); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey tagged successfully", nil)
+}
+
+// UntagSurvey 移除问卷标签
+func UntagSurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string   `json:"surveyId" binding:"required"`
+		Tags     []string `json:"tags" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.UntagSurvey(userID, request.SurveyID, request.Tags); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey untagged successfully", nil)
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBankQuestions 搜索题库题目
+func ListBankQuestions(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	questions, err := services.ListBankQuestions(userID, c.Query("keyword"), c.Query("tag"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Bank questions retrieved successfully", gin.H{
+		"data": questions,
+	})
+}
+
+// ListBankTags 获取题库标签
+func ListBankTags(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tags, err := services.ListBankTags(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tags retrieved successfully", gin.H{
+		"data": tags,
+	})
+}
+
+// SaveQuestionToBank 将问卷题目保存到题库
+func SaveQuestionToBank(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID   string   `json:"surveyId" binding:"required"`
+		QuestionID string   `json:"questionId" binding:"required"`
+		Tags       []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	bankQuestionID, revision, err := services.SaveQuestionToBank(userID, request.SurveyID, request.QuestionID, request.Tags)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Question saved to bank successfully", gin.H{
+		"bankQuestionId": bankQuestionID,
+		"revision":       revision,
+	})
+}
+
+// SetBankQuestionTags 设置题库题目标签
+func SetBankQuestionTags(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		BankQuestionID string   `json:"bankQuestionId" binding:"required"`
+		Tags           []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.SetBankQuestionTags(userID, request.BankQuestionID, request.Tags); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tags updated successfully", nil)
+}
+
+// DeleteBankQuestion 删除题库题目
+func DeleteBankQuestion(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		BankQuestionID string `json:"bankQuestionId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.DeleteBankQuestion(userID, request.BankQuestionID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Bank question deleted successfully", nil)
+}
+
+// GetBankQuestionStats 对比题库题目在各问卷中的作答情况
+func GetBankQuestionStats(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	bankQuestionID := c.Query("bankQuestionId")
+	if bankQuestionID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "bankQuestionId is required")
+		return
+	}
+
+	stats, err := services.GetBankQuestionStats(userID, bankQuestionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Statistics retrieved successfully", gin.H{
+		"data": stats,
+	})
+}
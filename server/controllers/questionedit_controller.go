@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"server/services"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,8 +23,17 @@ func GetSurveyMetaController(c *gin.Context) {
 		return
 	}
 
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
 	// 调用服务层获取元数据
-	meta, err := services.GetSurveyMetaService(surveyId)
+	meta, err := services.GetSurveyMetaService(surveyId, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": err.Error(),
@@ -29,7 +42,8 @@ func GetSurveyMetaController(c *gin.Context) {
 		return
 	}
 
-	// 返回元数据
+	// 返回元数据，修订号通过 ETag 返回
+	c.Header("ETag", formatETag(meta.Revision))
 	c.JSON(http.StatusOK, meta)
 }
 
@@ -45,8 +59,17 @@ func GetSurveyQuestionsController(c *gin.Context) {
 		return
 	}
 
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
 	// 调用服务层获取数据
-	survey, err := services.GetSurveyQuestionsService(surveyId)
+	survey, err := services.GetSurveyQuestionsService(surveyId, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": err.Error(),
@@ -55,7 +78,8 @@ func GetSurveyQuestionsController(c *gin.Context) {
 		return
 	}
 
-	// 返回结果
+	// 返回结果，修订号通过 ETag 返回
+	c.Header("ETag", formatETag(survey.Revision))
 	c.JSON(http.StatusOK, survey)
 }
 
@@ -71,6 +95,25 @@ func SaveSurveyEditController(c *gin.Context) {
 		return
 	}
 
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	// 保存必须携带读取时的修订号
+	expectedRevision, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"message": "If-Match header with the current revision is required",
+			"code":    428,
+		})
+		return
+	}
+
 	// 解析请求体
 	var surveyData services.SurveyModel
 	if err := c.ShouldBindJSON(&surveyData); err != nil {
@@ -82,7 +125,18 @@ func SaveSurveyEditController(c *gin.Context) {
 	}
 
 	// 调用服务层逻辑保存问卷
-	err := services.SaveSurveyEditService(surveyId, &surveyData)
+	revision, err := services.SaveSurveyEditService(surveyId, userID, expectedRevision, &surveyData)
+	if errors.Is(err, services.ErrRevisionConflict) {
+		// 问卷已被他人修改，返回当前修订号
+		current, _ := services.GetSurveyRevision(surveyId)
+		c.Header("ETag", formatETag(current))
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  err.Error(),
+			"code":     409,
+			"revision": current,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": err.Error(),
@@ -92,8 +146,85 @@ func SaveSurveyEditController(c *gin.Context) {
 	}
 
 	// 返回成功响应
+	c.Header("ETag", formatETag(revision))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Survey updated successfully",
+		"code":     200,
+		"revision": revision,
+	})
+}
+
+// PublishSurveyDraftController 发布问卷草稿
+func PublishSurveyDraftController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	// 发布会用草稿覆盖已发布的题目，必须携带读取时的修订号
+	expectedRevision, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"message": "If-Match header with the current revision is required",
+			"code":    428,
+		})
+		return
+	}
+
+	revision, err := services.PublishSurveyDraftService(surveyId, userID, expectedRevision)
+	if errors.Is(err, services.ErrRevisionConflict) {
+		current, _ := services.GetSurveyRevision(surveyId)
+		c.Header("ETag", formatETag(current))
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  err.Error(),
+			"code":     409,
+			"revision": current,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.Header("ETag", formatETag(revision))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Survey published successfully",
+		"code":     200,
+		"revision": revision,
+	})
+}
+
+// DiscardSurveyDraftController 丢弃问卷草稿
+func DiscardSurveyDraftController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	if err := services.DiscardSurveyDraftService(surveyId, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Survey updated successfully",
+		"message": "Draft discarded successfully",
 		"code":    200,
 	})
 }
@@ -110,8 +241,17 @@ func DeleteSurveyController(c *gin.Context) {
 		return
 	}
 
-	// 调用服务层逻辑删除问卷
-	err := services.DeleteSurveyService(surveyId)
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	// 调用服务层逻辑删除问卷（移入回收站）
+	err = services.DeleteSurveyService(surveyId, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": err.Error(),
@@ -126,3 +266,325 @@ func DeleteSurveyController(c *gin.Context) {
 		"code":    200,
 	})
 }
+
+// ListSurveyVersionsController 获取问卷版本列表
+func ListSurveyVersionsController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	versions, err := services.ListSurveyVersions(surveyId, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Versions retrieved successfully",
+		"code":    200,
+		"data":    versions,
+	})
+}
+
+// GetSurveyVersionController 获取指定版本的问卷内容
+func GetSurveyVersionController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid version",
+			"code":    400,
+		})
+		return
+	}
+
+	detail, err := services.GetSurveyVersion(surveyId, userID, version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// DiffSurveyVersionsController 比较问卷的两个版本
+func DiffSurveyVersionsController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "from and to versions are required",
+			"code":    400,
+		})
+		return
+	}
+
+	diff, err := services.DiffSurveyVersions(surveyId, userID, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RollbackSurveyVersionController 将问卷恢复到指定版本
+func RollbackSurveyVersionController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	// 恢复会覆盖草稿，必须携带读取时的修订号
+	expectedRevision, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"message": "If-Match header with the current revision is required",
+			"code":    428,
+		})
+		return
+	}
+
+	var request struct {
+		Version int `json:"version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+			"code":    400,
+		})
+		return
+	}
+
+	newVersion, revision, err := services.RollbackSurveyVersion(surveyId, userID, request.Version, expectedRevision)
+	if errors.Is(err, services.ErrRevisionConflict) {
+		current, _ := services.GetSurveyRevision(surveyId)
+		c.Header("ETag", formatETag(current))
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  err.Error(),
+			"code":     409,
+			"revision": current,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.Header("ETag", formatETag(revision))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Survey rolled back successfully",
+		"code":     200,
+		"version":  newVersion,
+		"revision": revision,
+	})
+}
+
+// ImportSurveyTextController 将文本格式的题目解析后写入草稿，dryRun 时只返回解析结果
+func ImportSurveyTextController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	var request struct {
+		Text   string `json:"text" binding:"required"`
+		DryRun bool   `json:"dryRun"`
+		Mode   string `json:"mode"` // append / replace，默认 append
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+			"code":    400,
+		})
+		return
+	}
+	if request.Mode != "" && request.Mode != "append" && request.Mode != "replace" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "mode must be append or replace",
+			"code":    400,
+		})
+		return
+	}
+
+	parsed, parseErrors := services.ParseSurveyText(request.Text)
+	if request.DryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Text parsed",
+			"code":    200,
+			"valid":   len(parseErrors) == 0,
+			"survey":  parsed,
+			"errors":  parseErrors,
+		})
+		return
+	}
+	if len(parseErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to parse text",
+			"code":    400,
+			"errors":  parseErrors,
+		})
+		return
+	}
+
+	// 写入草稿同样需要携带读取时的修订号
+	expectedRevision, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"message": "If-Match header with the current revision is required",
+			"code":    428,
+		})
+		return
+	}
+
+	revision, err := services.ImportSurveyTextService(surveyId, userID, expectedRevision, parsed, request.Mode == "replace")
+	if errors.Is(err, services.ErrRevisionConflict) {
+		current, _ := services.GetSurveyRevision(surveyId)
+		c.Header("ETag", formatETag(current))
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  err.Error(),
+			"code":     409,
+			"revision": current,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.Header("ETag", formatETag(revision))
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Questions imported successfully",
+		"code":      200,
+		"revision":  revision,
+		"questions": len(parsed.Questions),
+	})
+}
+
+// InsertBankQuestionsController 将题库题目的副本追加到问卷草稿
+func InsertBankQuestionsController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	expectedRevision, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"message": "If-Match header with the current revision is required",
+			"code":    428,
+		})
+		return
+	}
+
+	var request struct {
+		BankQuestionIDs []string `json:"bankQuestionIds" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+			"code":    400,
+		})
+		return
+	}
+
+	revision, err := services.InsertBankQuestions(surveyId, userID, expectedRevision, request.BankQuestionIDs)
+	if errors.Is(err, services.ErrRevisionConflict) {
+		current, _ := services.GetSurveyRevision(surveyId)
+		c.Header("ETag", formatETag(current))
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  err.Error(),
+			"code":     409,
+			"revision": current,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.Header("ETag", formatETag(revision))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Questions inserted successfully",
+		"code":     200,
+		"revision": revision,
+	})
+}
+
+// formatETag 将修订号格式化为 ETag
+func formatETag(revision int) string {
+	return fmt.Sprintf("\"%d\"", revision)
+}
+
+// parseIfMatch 从 If-Match 请求头解析修订号
+func parseIfMatch(header string) (int, bool) {
+	value := strings.TrimSpace(header)
+	value = strings.TrimPrefix(value, "W/")
+	value = strings.Trim(value, "\"")
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 0 {
+		return 0, false
+	}
+	return revision, true
+}
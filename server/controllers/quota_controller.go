@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListQuotas 获取问卷配额及填充情况
+func ListQuotas(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	surveyID := c.Query("surveyId")
+	if surveyID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "surveyId is required")
+		return
+	}
+
+	quotas, err := services.ListQuotas(surveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Quotas retrieved successfully", gin.H{
+		"data": quotas,
+	})
+}
+
+// CreateQuota 创建配额
+func CreateQuota(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string `json:"surveyId" binding:"required"`
+		services.QuotaRequest
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	quota, err := services.CreateQuota(request.SurveyID, userID, request.QuotaRequest)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Quota created successfully", gin.H{
+		"quota": quota,
+	})
+}
+
+// UpdateQuota 修改配额
+func UpdateQuota(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		QuotaID string `json:"quotaId" binding:"required"`
+		services.QuotaRequest
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	quota, err := services.UpdateQuota(request.QuotaID, userID, request.QuotaRequest)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Quota updated successfully", gin.H{
+		"quota": quota,
+	})
+}
+
+// DeleteQuota 删除配额
+func DeleteQuota(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		QuotaID string `json:"quotaId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.DeleteQuota(request.QuotaID, userID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Quota deleted successfully", nil)
+}
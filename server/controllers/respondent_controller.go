@@ -1,13 +1,32 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"server/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// 答题者随机种子 Cookie，保证刷新页面后题目和选项顺序不变
+const (
+	respondentSeedCookie = "respondent_seed"
+	respondentSeedMaxAge = 365 * 24 * 3600
+)
+
+// respondentLanguage 答题者请求的语言：lang 参数优先，其次为 Accept-Language 请求头
+func respondentLanguage(c *gin.Context, requested string) services.LanguageRequest {
+	if requested == "" {
+		requested = c.Query("lang")
+	}
+	return services.LanguageRequest{
+		Requested:      requested,
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+	}
+}
+
 // GetRespondentQuestionsController 获取答卷问题信息
 func GetRespondentQuestionsController(c *gin.Context) {
 	surveyId := c.Param("surveyId")
@@ -19,8 +38,18 @@ func GetRespondentQuestionsController(c *gin.Context) {
 		return
 	}
 
+	// 答题者的随机种子：优先使用请求参数，其次使用 Cookie，都没有时生成新的种子
+	seed := c.Query("seed")
+	if seed == "" {
+		seed, _ = c.Cookie(respondentSeedCookie)
+	}
+	if seed == "" || len(seed) > 64 {
+		seed = uuid.New().String()
+	}
+	c.SetCookie(respondentSeedCookie, seed, respondentSeedMaxAge, "/", "", false, true)
+
 	// 调用服务层获取问卷数据
-	survey, err := services.GetRespondentQuestionsController(surveyId)
+	survey, err := services.GetRespondentQuestionsController(surveyId, seed, respondentLanguage(c, ""))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": err.Error(),
@@ -33,6 +62,64 @@ func GetRespondentQuestionsController(c *gin.Context) {
 	c.JSON(http.StatusOK, survey)
 }
 
+// RenderRespondentQuestionsController 根据已填写的答案替换题目中引用前面答案的占位符
+func RenderRespondentQuestionsController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+
+	var request services.ResponseModel
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+			"code":    400,
+		})
+		return
+	}
+
+	// 使用与获取问卷时相同的种子，保证顺序一致
+	seed := request.Seed
+	if seed == "" {
+		seed, _ = c.Cookie(respondentSeedCookie)
+	}
+	if len(seed) > 64 {
+		seed = ""
+	}
+
+	survey, err := services.RenderRespondentQuestionsService(surveyId, seed, respondentLanguage(c, request.Language), request.QuestionsResponse)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
+// GetSurveyPreviewController 预览问卷草稿，仅问卷所有者可用
+func GetSurveyPreviewController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	survey, err := services.GetSurveyPreviewService(surveyId, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
 // SubmitSurveyResponseController 提交答卷
 func SubmitSurveyResponseController(c *gin.Context) {
 	surveyId := c.Param("surveyId")
@@ -64,8 +151,58 @@ func SubmitSurveyResponseController(c *gin.Context) {
 		return
 	}
 
+	// 未携带种子时使用获取问卷时设置的 Cookie
+	if responseModel.Seed == "" {
+		responseModel.Seed, _ = c.Cookie(respondentSeedCookie)
+	}
+	if len(responseModel.Seed) > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid seed",
+			"code":    400,
+		})
+		return
+	}
+
 	// 调用服务层保存答卷
-	err := services.SubmitSurveyResponseService(responseModel)
+	language := respondentLanguage(c, responseModel.Language)
+	responseModel.Language = language.Requested
+	result, err := services.SubmitSurveyResponseService(responseModel, language.AcceptLanguage)
+	var validationErr *services.ResponseValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Response validation failed",
+			"code":    400,
+			"errors":  validationErr.Errors,
+		})
+		return
+	}
+
+	// 配额已满时答卷不会保存，按配额动作告知答题者
+	var quotaErr *services.QuotaFullError
+	if errors.As(err, &quotaErr) {
+		switch quotaErr.Action {
+		case services.QuotaActionEnd:
+			c.JSON(http.StatusOK, gin.H{
+				"message": quotaErr.Error(),
+				"code":    200,
+				"action":  quotaErr.Action,
+			})
+		case services.QuotaActionRedirect:
+			c.JSON(http.StatusOK, gin.H{
+				"message":     quotaErr.Error(),
+				"code":        200,
+				"action":      quotaErr.Action,
+				"redirectUrl": quotaErr.RedirectURL,
+			})
+		default:
+			c.JSON(http.StatusConflict, gin.H{
+				"message": quotaErr.Error(),
+				"code":    409,
+				"action":  services.QuotaActionReject,
+			})
+		}
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -74,8 +211,13 @@ func SubmitSurveyResponseController(c *gin.Context) {
 		return
 	}
 
-	// 返回成功响应
-	c.JSON(http.StatusOK, gin.H{
+	// 返回成功响应，测验允许显示得分时附带得分
+	body := gin.H{
 		"message": "Response submitted successfully",
-	})
+	}
+	if result.Score != nil {
+		body["score"] = *result.Score
+		body["maxScore"] = result.MaxScore
+	}
+	c.JSON(http.StatusOK, body)
 }
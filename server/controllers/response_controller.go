@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"server/services"
 	"server/utils"
@@ -39,6 +41,39 @@ func GetOptionCount(c *gin.Context) {
 	})
 }
 
+// GetChoiceStats 获取选择题的选项分布和分值均值、中位数
+func GetChoiceStats(c *gin.Context) {
+	// 从路径中获取 SurveyID
+	surveyID := c.Param("SurveyID")
+	if surveyID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "SurveyID is required")
+		return
+	}
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// 从请求体中获取 QuestionID
+	var request struct {
+		QuestionID string `json:"QuestionID" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	stats, err := services.GetChoiceStats(surveyID, userID, request.QuestionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Choice stats retrieved successfully", gin.H{
+		"stats": stats,
+	})
+}
+
 // GetTextFillinData 获取指定 TextFillinID 的文本数据
 func GetTextFillinData(c *gin.Context) {
 	// 从路径参数中获取 SurveyID
@@ -101,7 +136,7 @@ func GetNumFillinData(c *gin.Context) {
 	})
 }
 
-// GetSurveyResponsesHandler 获取指定问卷的所有答卷内容
+// GetSurveyResponsesHandler 获取指定问卷的所有答卷内容，仅问卷所有者可用
 func GetSurveyResponsesHandler(c *gin.Context) {
 	// 获取 SurveyID 参数
 	surveyID := c.Param("SurveyID")
@@ -109,13 +144,142 @@ func GetSurveyResponsesHandler(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "SurveyID is required")
 		return
 	}
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// 可按计算变量筛选，如 ?filter=anxiety>=10
+	filters, err := services.ParseVariableFilters(c.QueryArray("filter"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 调用服务层逻辑
-	responses, err := services.GetSurveyResponses(surveyID)
+	responses, err := services.GetSurveyResponses(surveyID, userID, filters)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, responses)
 }
+
+// GetOtherTexts 获取“其他”选项收集到的文本，仅问卷所有者可用
+func GetOtherTexts(c *gin.Context) {
+	// 从路径参数中获取 SurveyID
+	surveyID := c.Param("SurveyID")
+	if surveyID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "SurveyID is required")
+		return
+	}
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// 请求体中的 QuestionID 可选，为空时返回所有题目
+	var request struct {
+		QuestionID string `json:"QuestionID"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	// 调用服务层逻辑
+	groups, err := services.GetOtherTexts(surveyID, userID, request.QuestionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Other texts retrieved successfully", gin.H{
+		"options": groups,
+	})
+}
+
+// ExportSurveyResponsesHandler 将问卷答卷导出为 CSV 文件，仅问卷所有者可用
+func ExportSurveyResponsesHandler(c *gin.Context) {
+	surveyID := c.Param("SurveyID")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	filters, err := services.ParseVariableFilters(c.QueryArray("filter"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// mode=codes 时选择题导出选项分值，默认导出选项内容
+	mode := c.DefaultQuery("mode", "labels")
+	if mode != "labels" && mode != "codes" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "mode must be labels or codes")
+		return
+	}
+
+	rows, err := services.ExportSurveyResponses(surveyID, userID, filters, mode == "codes")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"responses-%s.csv\"", surveyID))
+	c.Status(http.StatusOK)
+
+	// 写入 UTF-8 BOM，便于 Excel 正确识别中文
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	writer := csv.NewWriter(c.Writer)
+	writer.WriteAll(rows)
+}
+
+// GetQuizStatsHandler 获取测验的得分分布和各题难度
+func GetQuizStatsHandler(c *gin.Context) {
+	surveyID := c.Param("SurveyID")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	stats, err := services.GetQuizStats(surveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Quiz stats retrieved successfully", gin.H{
+		"stats": stats,
+	})
+}
+
+// GetVariableStatsHandler 获取计算变量的统计结果，可按计算变量筛选答卷
+func GetVariableStatsHandler(c *gin.Context) {
+	surveyID := c.Param("SurveyID")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	filters, err := services.ParseVariableFilters(c.QueryArray("filter"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := services.GetVariableStats(surveyID, userID, filters)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Variable stats retrieved successfully", gin.H{
+		"variables": stats,
+	})
+}
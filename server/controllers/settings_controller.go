@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"server/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSurveySettingsController 获取问卷的外观和答题设置
+func GetSurveySettingsController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	settings, err := services.GetSurveySettings(surveyId, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	// 修订号通过 ETag 返回
+	c.Header("ETag", formatETag(settings.Revision))
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSurveySettingsController 修改问卷的外观和答题设置
+func UpdateSurveySettingsController(c *gin.Context) {
+	surveyId := c.Param("surveyId")
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": err.Error(),
+			"code":    401,
+		})
+		return
+	}
+
+	// 修改设置必须携带读取时的修订号
+	expectedRevision, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"message": "If-Match header with the current revision is required",
+			"code":    428,
+		})
+		return
+	}
+
+	var request services.SurveySettingsUpdate
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request body",
+			"code":    400,
+		})
+		return
+	}
+
+	settings, err := services.UpdateSurveySettings(surveyId, userID, expectedRevision, request)
+	if errors.Is(err, services.ErrRevisionConflict) {
+		current, _ := services.GetSurveyRevision(surveyId)
+		c.Header("ETag", formatETag(current))
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  err.Error(),
+			"code":     409,
+			"revision": current,
+		})
+		return
+	}
+	var settingsErr *services.SurveySettingsError
+	if errors.As(err, &settingsErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid survey settings",
+			"code":    400,
+			"errors":  settingsErr.Errors,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+			"code":    400,
+		})
+		return
+	}
+
+	c.Header("ETag", formatETag(settings.Revision))
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Survey settings updated successfully",
+		"code":       200,
+		"revision":   settings.Revision,
+		"appearance": settings.Appearance,
+		"settings":   settings.Settings,
+	})
+}
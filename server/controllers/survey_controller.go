@@ -1,16 +1,15 @@
 package controllers
 
 import (
-	"math/rand"
+	"errors"
 	"net/http"
-	"server/common"
 	"server/services"
 	"server/utils"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // 问卷创建
@@ -34,44 +33,9 @@ func CreateSurvey(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
 		return
 	}
-	now := time.Now()
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	accessID := rng.Intn(900000) + 100000 // 生成六位数字（100000到999999）
 	// 调用服务层创建问卷
 	// 构造问卷对象
-	survey := common.Survey{
-		SurveyID:          uuid.New().String(),
-		AccessID:          strconv.Itoa(accessID),
-		Title:             request.Title,
-		Description:       "", // 默认描述为空字符串，可以根据需要调整
-		CreateTime:        now,
-		ExpireTime:        now.AddDate(0, 1, 0), // 默认过期时间为 1 个月后
-		LastUpdateTime:    now,
-		Status:            "Ongoing",
-		ResponseCount:     0,                                                                            // 初始响应数量为 0
-		ThemeColor:        0,                                                                            // 默认主题颜色
-		TextColor:         0,                                                                            // 默认文字颜色
-		PCBackgroundImage: "",                                                                           // 默认背景图片为空
-		PCBannerImage:     "",                                                                           // 默认横幅图片为空
-		Footer:            nil,                                                                          // 页脚默认值为空
-		DisplayStyle:      0,                                                                            // 默认显示样式
-		ButtonText:        nil,                                                                          // 按钮文字默认值为空
-		StartTime:         now,                                                                          // 默认开始时间为当前时间
-		EndTime:           now.AddDate(0, 1, 0),                                                         // 默认结束时间为 1 个月后
-		DayStartTime:      time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),    // 默认每日开始时间为 00:00
-		DayEndTime:        time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, now.Location()), // 默认每日结束时间为 23:59
-		PasswordStrategy:  0,                                                                            // 默认密码策略
-		Password:          "{}",                                                                         // 默认无密码
-		MaxResponseCount:  0,                                                                            // 默认无限制
-		BrowserLimit:      false,                                                                        // 默认不限制浏览器
-		IPLimit:           false,                                                                        // 默认不限制 IP
-		KeepContent:       false,                                                                        // 默认不保留内容
-		FailMessage:       "",                                                                           // 默认失败消息为空
-		ShowAfterSubmit:   0,                                                                            // 默认提交后不显示内容
-		ShowContent:       "",                                                                           // 默认显示内容为空
-		QuestionIDs:       "{}",                                                                         // 默认无问题列表
-		ResponseIDs:       nil,                                                                          // 默认无响应列表
-	}
+	survey := services.NewDefaultSurvey(request.Title)
 	if err := services.CreateSurvey(userID, &survey); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create survey")
 		return
@@ -107,12 +71,15 @@ func ListSurveys(c *gin.Context) {
 		return
 	}
 
-	// 获取分页参数
-	count, _ := strconv.Atoi(c.DefaultQuery("count", "10"))
-	skip, _ := strconv.Atoi(c.DefaultQuery("skip", "0"))
+	// 获取分页、搜索、筛选与排序参数
+	query, err := parseSurveyListQuery(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 调用服务层获取问卷列表
-	surveys, total, length, err := services.ListSurveys(userID, count, skip)
+	surveys, total, length, nextCursor, err := services.ListSurveys(userID, query)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve surveys")
 		return
@@ -122,14 +89,77 @@ func ListSurveys(c *gin.Context) {
 		surveys = []services.SurveyResponse{} // 将 nil 替换为空数组
 	}
 	utils.SuccessResponse(c, http.StatusOK, "Surveys retrieved successfully", gin.H{
-		"data":   surveys, // surveys 直接作为数组返回
-		"total":  total,   // 总数
-		"length": length,  // 当前分页的数量
+		"data":       surveys,    // surveys 直接作为数组返回
+		"total":      total,      // 总数
+		"length":     length,     // 当前分页的数量
+		"nextCursor": nextCursor, // 下一页游标，为空表示没有更多数据
 	})
 }
 
-// UpdateSurveyStatus 更新问卷状态（开始/暂停/删除）
+// parseSurveyListQuery 解析问卷列表查询参数
+func parseSurveyListQuery(c *gin.Context) (services.SurveyListQuery, error) {
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "10"))
+	skip, _ := strconv.Atoi(c.DefaultQuery("skip", "0"))
+	query := services.SurveyListQuery{
+		Keyword:   strings.TrimSpace(c.Query("keyword")),
+		SortBy:    c.DefaultQuery("sortBy", "createTime"),
+		Ascending: c.DefaultQuery("order", "desc") == "asc",
+		Cursor:    c.Query("cursor"),
+		Count:     count,
+		Skip:      skip,
+	}
+
+	if status := c.Query("status"); status != "" {
+		query.Statuses = strings.Split(status, ",")
+	}
+	if folderID, ok := c.GetQuery("folderId"); ok {
+		query.FolderID = &folderID // 空字符串表示根目录
+	}
+	query.Tag = strings.TrimSpace(c.Query("tag"))
+
+	// 解析时间范围
+	var err error
+	if query.CreatedFrom, err = parseQueryTime(c.Query("createdFrom"), false); err != nil {
+		return query, errors.New("invalid createdFrom")
+	}
+	if query.CreatedTo, err = parseQueryTime(c.Query("createdTo"), true); err != nil {
+		return query, errors.New("invalid createdTo")
+	}
+	if query.UpdatedFrom, err = parseQueryTime(c.Query("updatedFrom"), false); err != nil {
+		return query, errors.New("invalid updatedFrom")
+	}
+	if query.UpdatedTo, err = parseQueryTime(c.Query("updatedTo"), true); err != nil {
+		return query, errors.New("invalid updatedTo")
+	}
+	return query, nil
+}
+
+// parseQueryTime 解析 RFC3339 或 YYYY-MM-DD 格式的时间，日期作为上限时包含当天
+func parseQueryTime(value string, upperBound bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// UpdateSurveyStatus 更新问卷状态（发布/暂停/结束/归档）
 func UpdateSurveyStatus(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
 	var statusUpdate struct {
 		SurveyID string `json:"surveyId"`
 		Status   string `json:"status"`
@@ -140,7 +170,7 @@ func UpdateSurveyStatus(c *gin.Context) {
 		return
 	}
 
-	if err := services.UpdateSurveyStatus(statusUpdate.SurveyID, statusUpdate.Status); err != nil {
+	if err := services.UpdateSurveyStatus(statusUpdate.SurveyID, statusUpdate.Status, userID); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -150,6 +180,12 @@ func UpdateSurveyStatus(c *gin.Context) {
 
 // CopySurvey 复制问卷
 func CopySurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
 	var copyRequest struct {
 		SurveyID string `json:"surveyId"`
 	}
@@ -159,11 +195,180 @@ func CopySurvey(c *gin.Context) {
 		return
 	}
 
-	err := services.CopySurvey(copyRequest.SurveyID)
+	newSurveyID, err := services.CopySurvey(copyRequest.SurveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey copied successfully", gin.H{
+		"surveyId": newSurveyID,
+	})
+}
+
+// GetSurveyStatusHistory 获取问卷状态变更历史
+func GetSurveyStatusHistory(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	surveyID := c.Query("surveyId")
+	if surveyID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "surveyId is required")
+		return
+	}
+
+	histories, err := services.GetSurveyStatusHistory(surveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Status history retrieved successfully", gin.H{
+		"data": histories,
+	})
+}
+
+// ResolveAccessID 通过短链接访问 ID 获取问卷
+func ResolveAccessID(c *gin.Context) {
+	accessID := c.Param("accessId")
+	if accessID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "accessId is required")
+		return
+	}
+
+	survey, err := services.ResolveAccessID(accessID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey resolved successfully", gin.H{
+		"surveyId": survey.SurveyID,
+		"accessId": survey.AccessID,
+		"title":    survey.Title,
+	})
+}
+
+// RegenerateAccessID 重新生成问卷访问 ID
+func RegenerateAccessID(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string `json:"surveyId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	accessID, err := services.RegenerateAccessID(request.SurveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Access ID regenerated successfully", gin.H{
+		"accessId": accessID,
+	})
+}
+
+// SetCustomAccessID 设置自定义访问 ID
+func SetCustomAccessID(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string `json:"surveyId" binding:"required"`
+		AccessID string `json:"accessId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.SetCustomAccessID(request.SurveyID, userID, request.AccessID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Access ID updated successfully", gin.H{
+		"accessId": request.AccessID,
+	})
+}
+
+// ListTrash 获取回收站中的问卷
+func ListTrash(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	surveys, err := services.ListTrash(userID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Survey copied successfully", nil)
+	utils.SuccessResponse(c, http.StatusOK, "Trash retrieved successfully", gin.H{
+		"data": surveys,
+	})
+}
+
+// RestoreSurvey 从回收站恢复问卷
+func RestoreSurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string `json:"surveyId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.RestoreSurvey(request.SurveyID, userID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey restored successfully", nil)
+}
+
+// PurgeSurvey 永久删除回收站中的问卷
+func PurgeSurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string `json:"surveyId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.PurgeSurvey(request.SurveyID, userID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey permanently deleted", nil)
 }
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportSurvey 导出问卷定义文档
+func ExportSurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	surveyID := c.Query("surveyId")
+	if surveyID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "surveyId is required")
+		return
+	}
+
+	doc, err := services.ExportSurveyService(surveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"survey-%s.json\"", surveyID))
+	c.JSON(http.StatusOK, doc)
+}
+
+// ImportSurvey 导入问卷定义文档，创建新问卷
+func ImportSurvey(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	doc, err := services.ParseSurveyDocument(data)
+	if docErr, ok := services.AsSurveyDocumentError(err); ok {
+		utils.JSONResponse(c, http.StatusBadRequest, "Invalid survey document", gin.H{
+			"errors": docErr.Errors,
+		})
+		return
+	}
+
+	surveyID, err := services.ImportSurveyService(userID, doc)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey imported successfully", gin.H{
+		"surveyId": surveyID,
+	})
+}
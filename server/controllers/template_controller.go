@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTemplates 获取可用的问卷模板
+func ListTemplates(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	templates, err := services.ListTemplates(userID, c.Query("category"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Templates retrieved successfully", gin.H{
+		"data": templates,
+	})
+}
+
+// SaveSurveyAsTemplate 将问卷保存为模板
+func SaveSurveyAsTemplate(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID    string `json:"surveyId" binding:"required"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Category    string `json:"category"`
+		Shared      bool   `json:"shared"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	template, err := services.SaveSurveyAsTemplate(userID, request.SurveyID, request.Name, request.Description, request.Category, request.Shared)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Template saved successfully", gin.H{
+		"template": template,
+	})
+}
+
+// CreateSurveyFromTemplate 基于模板创建问卷
+func CreateSurveyFromTemplate(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		TemplateID string `json:"templateId" binding:"required"`
+		Title      string `json:"title"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	surveyID, err := services.CreateSurveyFromTemplate(userID, request.TemplateID, request.Title)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Survey created successfully", gin.H{
+		"surveyId": surveyID,
+	})
+}
+
+// DeleteTemplate 删除模板
+func DeleteTemplate(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		TemplateID string `json:"templateId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	if err := services.DeleteTemplate(userID, request.TemplateID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Template deleted successfully", nil)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"server/services"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTranslationSettings 获取问卷的语言设置
+func GetTranslationSettings(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	surveyID := c.Query("surveyId")
+	if surveyID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "surveyId is required")
+		return
+	}
+
+	settings, err := services.GetTranslationSettings(surveyID, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Languages retrieved successfully", gin.H{
+		"defaultLanguage": settings.DefaultLanguage,
+		"languages":       settings.Languages,
+	})
+}
+
+// UpdateTranslationSettings 修改问卷的默认语言和其他语言
+func UpdateTranslationSettings(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var request struct {
+		SurveyID string `json:"surveyId" binding:"required"`
+		services.TranslationSettings
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	settings, err := services.UpdateTranslationSettings(request.SurveyID, userID, request.TranslationSettings)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Languages updated successfully", gin.H{
+		"defaultLanguage": settings.DefaultLanguage,
+		"languages":       settings.Languages,
+	})
+}
+
+// ExportTranslations 导出指定语言的译文文档
+func ExportTranslations(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	surveyID := c.Query("surveyId")
+	language := c.Query("lang")
+	if surveyID == "" || language == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "surveyId and lang are required")
+		return
+	}
+
+	doc, err := services.ExportTranslations(surveyID, userID, language)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\"translation-"+surveyID+"-"+doc.Language+".json\"")
+	c.JSON(http.StatusOK, doc)
+}
+
+// ImportTranslations 导入译文文档
+func ImportTranslations(c *gin.Context) {
+	userID, err := services.GetUserID(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var doc services.TranslationDocument
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+	if doc.SurveyID == "" || doc.Language == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "surveyId and language are required")
+		return
+	}
+
+	updated, errs, err := services.ImportTranslations(doc.SurveyID, userID, doc)
+	if err != nil {
+		utils.JSONResponse(c, http.StatusBadRequest, err.Error(), gin.H{
+			"errors": errs,
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Translations imported successfully", gin.H{
+		"updated": updated,
+	})
+}
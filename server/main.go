@@ -14,8 +14,27 @@ func main() {
 	// 加载配置
 	config.LoadConfig()
 	services.InitAuthConfig()
+	services.InitTrashConfig()
+	services.InitFileConfig()
 	common.InitDb()
 
+	// 建立访问 ID 唯一索引前，修复旧数据中重复或为空的访问 ID
+	if err := services.RepairAccessIDs(); err != nil {
+		panic("failed to repair access IDs: " + err.Error())
+	}
+	common.MigrateDb()
+
+	// 加载内置问卷模板
+	if err := services.SeedBuiltinTemplates(); err != nil {
+		panic(err)
+	}
+
+	// 启动回收站清理任务
+	services.StartTrashPurgeJob()
+
+	// 启动未引用文件清理任务
+	services.StartFileGCJob()
+
 	// 打印加载的配置（可选）
 	fmt.Printf("Loaded config: %+v\n", config.Config)
 
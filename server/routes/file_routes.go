@@ -1 +1,16 @@
 package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterFileRoutes 注册文件上传相关路由
+func RegisterFileRoutes(router *gin.RouterGroup) {
+	fileGroup := router.Group("/file")
+	{
+		fileGroup.POST("/upload", controllers.UploadFile) // 上传图片
+		fileGroup.GET("/:fileId", controllers.GetFile)    // 获取文件
+	}
+}
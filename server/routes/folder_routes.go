@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterFolderRoutes 注册文件夹相关路由
+func RegisterFolderRoutes(router *gin.RouterGroup) {
+	folderGroup := router.Group("/folder")
+	{
+		folderGroup.GET("", controllers.ListFolders)              // 获取文件夹列表
+		folderGroup.POST("/create", controllers.CreateFolder)     // 创建文件夹
+		folderGroup.POST("/rename", controllers.RenameFolder)     // 重命名文件夹
+		folderGroup.POST("/move", controllers.MoveFolder)         // 移动文件夹
+		folderGroup.POST("/delete", controllers.DeleteFolder)     // 删除文件夹
+		folderGroup.POST("/moveSurveys", controllers.MoveSurveys) // 批量移动问卷
+	}
+}
@@ -14,5 +14,12 @@ func RegisterRoutes(router *gin.Engine) {
 		RegisterRespondentRoutes(apiGroup)   // 注册答卷相关路由
 		RegisterResponseRoutes(apiGroup)     // 注册答卷内容相关路由
 		RegisterQuestionEditRoutes(apiGroup) // 注册问题编辑相关路由
+		RegisterShareRoutes(apiGroup)        // 注册短链接相关路由
+		RegisterFolderRoutes(apiGroup)       // 注册文件夹相关路由
+		RegisterTemplateRoutes(apiGroup)     // 注册问卷模板相关路由
+		RegisterQuestionBankRoutes(apiGroup) // 注册题库相关路由
+		RegisterQuotaRoutes(apiGroup)        // 注册问卷配额相关路由
+		RegisterTranslationRoutes(apiGroup)  // 注册问卷多语言相关路由
+		RegisterFileRoutes(apiGroup)         // 注册文件上传相关路由
 	}
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterQuestionBankRoutes 注册题库相关路由
+func RegisterQuestionBankRoutes(router *gin.RouterGroup) {
+	bankGroup := router.Group("/bank")
+	{
+		bankGroup.GET("", controllers.ListBankQuestions)          // 搜索题库
+		bankGroup.GET("/tags", controllers.ListBankTags)          // 获取题库标签
+		bankGroup.GET("/stats", controllers.GetBankQuestionStats) // 跨问卷作答对比
+		bankGroup.POST("/save", controllers.SaveQuestionToBank)   // 将题目保存到题库
+		bankGroup.POST("/tag", controllers.SetBankQuestionTags)   // 设置题目标签
+		bankGroup.POST("/delete", controllers.DeleteBankQuestion) // 删除题库题目
+	}
+}
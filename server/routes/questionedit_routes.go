@@ -12,7 +12,18 @@ func RegisterQuestionEditRoutes(router *gin.RouterGroup) {
 	{
 		editGroup.GET("/:surveyId/meta", controllers.GetSurveyMetaController)
 		editGroup.GET("/:surveyId/questions", controllers.GetSurveyQuestionsController)
+		editGroup.GET("/:surveyId/settings", controllers.GetSurveySettingsController)
+		editGroup.POST("/:surveyId/settings", controllers.UpdateSurveySettingsController)
 		editGroup.POST("/:surveyId/qedit", controllers.SaveSurveyEditController)
+		editGroup.POST("/:surveyId/publish", controllers.PublishSurveyDraftController)
+		editGroup.POST("/:surveyId/discard", controllers.DiscardSurveyDraftController)
 		editGroup.DELETE("/:surveyId/delete", controllers.DeleteSurveyController)
+		editGroup.POST("/:surveyId/import-text", controllers.ImportSurveyTextController)
+		editGroup.POST("/:surveyId/insert-bank", controllers.InsertBankQuestionsController)
+
+		editGroup.GET("/:surveyId/versions", controllers.ListSurveyVersionsController)
+		editGroup.GET("/:surveyId/versions/diff", controllers.DiffSurveyVersionsController)
+		editGroup.GET("/:surveyId/versions/:version", controllers.GetSurveyVersionController)
+		editGroup.POST("/:surveyId/rollback", controllers.RollbackSurveyVersionController)
 	}
 }
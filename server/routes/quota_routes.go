@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterQuotaRoutes 注册问卷配额相关路由
+func RegisterQuotaRoutes(router *gin.RouterGroup) {
+	quotaGroup := router.Group("/quota")
+	{
+		quotaGroup.GET("", controllers.ListQuotas)          // 获取配额及填充情况
+		quotaGroup.POST("/create", controllers.CreateQuota) // 创建配额
+		quotaGroup.POST("/update", controllers.UpdateQuota) // 修改配额
+		quotaGroup.POST("/delete", controllers.DeleteQuota) // 删除配额
+	}
+}
@@ -12,5 +12,7 @@ func RegisterRespondentRoutes(apiGroup *gin.RouterGroup) {
 	{
 		responseRoutes.GET("/:surveyId/questions", controllers.GetRespondentQuestionsController)
 		responseRoutes.POST("/:surveyId/submit", controllers.SubmitSurveyResponseController)
+		responseRoutes.POST("/:surveyId/render", controllers.RenderRespondentQuestionsController)
+		responseRoutes.GET("/:surveyId/preview", controllers.GetSurveyPreviewController)
 	}
 }
@@ -10,7 +10,12 @@ import (
 func RegisterResponseRoutes(group *gin.RouterGroup) {
 	surveyGroup := group.Group("/survey")
 	surveyGroup.POST("/:SurveyID/GetOption", controllers.GetOptionCount)
+	surveyGroup.POST("/:SurveyID/GetChoice", controllers.GetChoiceStats)
 	surveyGroup.POST("/:SurveyID/GetText", controllers.GetTextFillinData)
 	surveyGroup.POST("/:SurveyID/GetNum", controllers.GetNumFillinData)
+	surveyGroup.POST("/:SurveyID/GetOther", controllers.GetOtherTexts)
+	surveyGroup.GET("/:SurveyID/export", controllers.ExportSurveyResponsesHandler)
+	surveyGroup.GET("/:SurveyID/quiz-stats", controllers.GetQuizStatsHandler)
+	surveyGroup.GET("/:SurveyID/variables", controllers.GetVariableStatsHandler)
 	surveyGroup.GET("/:SurveyID", controllers.GetSurveyResponsesHandler)
 }
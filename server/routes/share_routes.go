@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterShareRoutes 注册短链接相关路由
+func RegisterShareRoutes(router *gin.RouterGroup) {
+	shareGroup := router.Group("/s")
+	{
+		shareGroup.GET("/:accessId", controllers.ResolveAccessID) // 解析访问 ID
+	}
+}
@@ -13,5 +13,20 @@ func RegisterSurveyRoutes(router *gin.RouterGroup) {
 		surveyGroup.POST("/switch", controllers.UpdateSurveyStatus) // 修改问卷状态
 		surveyGroup.POST("/copy", controllers.CopySurvey)           // 问卷复制
 		surveyGroup.GET("", controllers.ListSurveys)                // 获取问卷列表
+
+		surveyGroup.GET("/history", controllers.GetSurveyStatusHistory)        // 问卷状态历史
+		surveyGroup.POST("/access/regenerate", controllers.RegenerateAccessID) // 重新生成访问 ID
+		surveyGroup.POST("/access/custom", controllers.SetCustomAccessID)      // 设置自定义访问 ID
+
+		surveyGroup.GET("/trash", controllers.ListTrash)        // 回收站列表
+		surveyGroup.POST("/restore", controllers.RestoreSurvey) // 从回收站恢复
+		surveyGroup.POST("/purge", controllers.PurgeSurvey)     // 永久删除
+
+		surveyGroup.GET("/tags", controllers.ListTags)      // 获取标签列表
+		surveyGroup.POST("/tag", controllers.TagSurvey)     // 添加标签
+		surveyGroup.POST("/untag", controllers.UntagSurvey) // 移除标签
+
+		surveyGroup.GET("/export", controllers.ExportSurvey)  // 导出问卷定义
+		surveyGroup.POST("/import", controllers.ImportSurvey) // 导入问卷定义
 	}
 }
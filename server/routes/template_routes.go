@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTemplateRoutes 注册问卷模板相关路由
+func RegisterTemplateRoutes(router *gin.RouterGroup) {
+	templateGroup := router.Group("/template")
+	{
+		templateGroup.GET("", controllers.ListTemplates)                    // 获取模板列表
+		templateGroup.POST("/save", controllers.SaveSurveyAsTemplate)       // 将问卷保存为模板
+		templateGroup.POST("/create", controllers.CreateSurveyFromTemplate) // 基于模板创建问卷
+		templateGroup.POST("/delete", controllers.DeleteTemplate)           // 删除模板
+	}
+}
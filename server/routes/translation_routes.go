@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"server/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTranslationRoutes 注册问卷多语言相关路由
+func RegisterTranslationRoutes(router *gin.RouterGroup) {
+	translationGroup := router.Group("/translation")
+	{
+		translationGroup.GET("/languages", controllers.GetTranslationSettings)     // 获取语言设置
+		translationGroup.POST("/languages", controllers.UpdateTranslationSettings) // 修改语言设置
+		translationGroup.GET("/export", controllers.ExportTranslations)            // 导出译文
+		translationGroup.POST("/import", controllers.ImportTranslations)           // 导入译文
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"server/config"
 	"time"
@@ -65,6 +66,19 @@ func GetCookie(c *gin.Context) (jwt.MapClaims, error) {
 	return ValidateJWT(token)
 }
 
+// GetUserID 从 Cookie 中提取当前登录用户的 ID
+func GetUserID(c *gin.Context) (string, error) {
+	claims, err := GetCookie(c)
+	if err != nil {
+		return "", errors.New("unauthorized: invalid or missing token")
+	}
+	userID, ok := claims["userID"].(string)
+	if !ok {
+		return "", errors.New("invalid token claims")
+	}
+	return userID, nil
+}
+
 // 删除 Cookie
 func DeleteCookie(c *gin.Context) {
 	c.SetCookie("token", "", -1, "/", c.Request.Host, true, true)
@@ -0,0 +1,676 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 计算变量和显示条件使用的表达式语言：
+//   - 数字、括号、变量名，Q3 表示第 3 题（按编辑时的题目顺序）的值
+//   - 算术运算 + - * /，比较运算 == != < <= > >=，逻辑运算 and or not，比较和逻辑运算的结果为 1 或 0
+//   - 函数 sum avg min max count 的参数可以是区间 Q4:Q10，只统计已作答的题目
+//   - 函数 abs(x)、round(x[, digits])、if(cond, a, b)、answered(Qn)
+//
+// 选择题的值为选中选项的分值，多选题为分值之和；数字填空题的值为各填空之和；未作答的题目值为 0
+
+const (
+	maxExpressionLength = 1000  // 表达式最大长度
+	maxExpressionDepth  = 50    // 最大嵌套层数
+	maxQuestionRef      = 10000 // 题目引用的最大序号
+)
+
+// questionRefPattern 题目引用，如 Q3
+var questionRefPattern = regexp.MustCompile(`^[Qq]([0-9]+)$`)
+
+// exprFunction 表达式函数的参数要求
+type exprFunction struct {
+	minArgs   int
+	maxArgs   int  // -1 表示不限
+	aggregate bool // 是否接受区间参数
+}
+
+var exprFunctions = map[string]exprFunction{
+	"sum":      {1, -1, true},
+	"avg":      {1, -1, true},
+	"min":      {1, -1, true},
+	"max":      {1, -1, true},
+	"count":    {1, -1, true},
+	"abs":      {1, 1, false},
+	"round":    {1, 2, false},
+	"if":       {3, 3, false},
+	"answered": {1, 1, false},
+}
+
+// exprKeywords 逻辑运算关键字
+var exprKeywords = map[string]bool{"and": true, "or": true, "not": true}
+
+// isReservedName 判断名称是否为关键字、函数名或题目引用，不能用作变量名
+func isReservedName(name string) bool {
+	lower := strings.ToLower(name)
+	_, isFunction := exprFunctions[lower]
+	return isFunction || exprKeywords[lower] || questionRefPattern.MatchString(name)
+}
+
+type exprTokenKind int
+
+const (
+	tokenNumber exprTokenKind = iota
+	tokenIdent
+	tokenOperator
+	tokenEOF
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	text  string
+	pos   int
+	value float64
+}
+
+// tokenizeExpression 将表达式拆分为记号
+func tokenizeExpression(source string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", source[start:i], start+1)
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: source[start:i], pos: start, value: value})
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_':
+			start := i
+			for i < len(source) && (source[i] >= 'a' && source[i] <= 'z' || source[i] >= 'A' && source[i] <= 'Z' || source[i] >= '0' && source[i] <= '9' || source[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[start:i], pos: start})
+		default:
+			if i+1 < len(source) {
+				switch two := source[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, exprToken{kind: tokenOperator, text: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/()<>,!:", rune(c)) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: string(c), pos: i})
+			i++
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(source)}), nil
+}
+
+type exprNode interface{}
+
+type numberNode struct{ value float64 }
+
+// questionNode 题目引用，index 从 0 开始
+type questionNode struct{ index int }
+
+// rangeNode 题目区间，只能作为聚合函数的参数
+type rangeNode struct{ from, to int }
+
+type variableNode struct{ name string }
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+// expression 已解析的表达式及其引用
+type expression struct {
+	root      exprNode
+	questions map[int]bool    // 引用的题目序号（从 0 开始）
+	ranges    []*rangeNode    // 聚合函数中的题目区间，不展开为单个题目
+	answered  map[int]bool    // 只通过 answered() 判断是否作答的题目序号
+	variables map[string]bool // 引用的变量名
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	depth  int
+	expr   *expression
+}
+
+// parseExpression 解析表达式，只允许表达式语言中定义的运算和函数
+func parseExpression(source string) (*expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, errors.New("expression is empty")
+	}
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression must be at most %d characters", maxExpressionLength)
+	}
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &exprParser{
+		tokens: tokens,
+		expr: &expression{
+			questions: map[int]bool{},
+			answered:  map[int]bool{},
+			variables: map[string]bool{},
+		},
+	}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.pos+1)
+	}
+	parser.expr.root = root
+	return parser.expr, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// accept 当前记号为指定运算符或关键字之一时前进并返回它
+func (p *exprParser) accept(texts ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != tokenOperator && token.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if token.kind == tokenIdent && strings.EqualFold(token.text, text) || token.kind == tokenOperator && token.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		token := p.peek()
+		if token.kind == tokenEOF {
+			return fmt.Errorf("expected %q at end of expression", text)
+		}
+		return fmt.Errorf("expected %q at position %d", text, token.pos+1)
+	}
+	return nil
+}
+
+func (p *exprParser) enter() error {
+	p.depth++
+	if p.depth > maxExpressionDepth {
+		return errors.New("expression is nested too deeply")
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "or", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "and", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseNot()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "not", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<=", ">=", "<", ">"); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op, ok := p.accept("-", "+"); ok {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return operand, nil
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// questionIndex 解析题目引用，返回从 0 开始的序号
+func questionIndex(token exprToken) (int, bool) {
+	if token.kind != tokenIdent {
+		return 0, false
+	}
+	match := questionRefPattern.FindStringSubmatch(token.text)
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n < 1 || n > maxQuestionRef {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenNumber:
+		return &numberNode{value: token.value}, nil
+	case tokenIdent:
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(token)
+		}
+		if exprKeywords[strings.ToLower(token.text)] {
+			return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.pos+1)
+		}
+		if index, ok := questionIndex(token); ok {
+			p.expr.questions[index] = true
+			return &questionNode{index: index}, nil
+		}
+		if questionRefPattern.MatchString(token.text) {
+			return nil, fmt.Errorf("invalid question reference %q at position %d", token.text, token.pos+1)
+		}
+		p.expr.variables[token.text] = true
+		return &variableNode{name: token.text}, nil
+	case tokenOperator:
+		if token.text == "(" {
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			node, err := p.parseOr()
+			p.depth--
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		}
+		return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.pos+1)
+	}
+	return nil, errors.New("unexpected end of expression")
+}
+
+// parseCall 解析函数调用，左括号已读取
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	functionName := strings.ToLower(name.text)
+	function, ok := exprFunctions[functionName]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos+1)
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	call := &callNode{name: functionName}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseArgument(functionName, function)
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(call.args) < function.minArgs || function.maxArgs >= 0 && len(call.args) > function.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for %s at position %d", functionName, name.pos+1)
+	}
+	return call, nil
+}
+
+// parseArgument 解析函数参数，聚合函数可以使用区间，answered 只接受题目引用
+func (p *exprParser) parseArgument(functionName string, function exprFunction) (exprNode, error) {
+	token := p.peek()
+	from, isQuestion := questionIndex(token)
+	if isQuestion && functionName == "answered" {
+		p.next()
+		p.expr.answered[from] = true
+		return &questionNode{index: from}, nil
+	}
+	if functionName == "answered" {
+		return nil, fmt.Errorf("answered expects a question reference at position %d", token.pos+1)
+	}
+
+	if isQuestion && function.aggregate && p.tokens[p.pos+1].kind == tokenOperator && p.tokens[p.pos+1].text == ":" {
+		p.pos += 2
+		end := p.next()
+		to, ok := questionIndex(end)
+		if !ok && questionRefPattern.MatchString(end.text) {
+			return nil, fmt.Errorf("invalid question reference %q at position %d", end.text, end.pos+1)
+		}
+		if !ok {
+			return nil, fmt.Errorf("expected a question reference at position %d", end.pos+1)
+		}
+		if to < from {
+			return nil, fmt.Errorf("invalid question range at position %d", token.pos+1)
+		}
+		node := &rangeNode{from: from, to: to}
+		p.expr.ranges = append(p.expr.ranges, node)
+		return node, nil
+	}
+	return p.parseOr()
+}
+
+// questionValue 题目的作答值
+type questionValue struct {
+	value    float64
+	answered bool
+}
+
+// exprEnv 表达式求值环境
+type exprEnv struct {
+	questions []questionValue
+	variables map[string]float64
+}
+
+// eval 计算表达式的值，除以 0 或引用未定义的变量时返回错误
+func (e *expression) eval(env *exprEnv) (float64, error) {
+	value, err := evalNode(e.root, env)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return value, nil
+}
+
+func (env *exprEnv) question(index int) questionValue {
+	if index < 0 || index >= len(env.questions) {
+		return questionValue{}
+	}
+	return env.questions[index]
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func evalNode(node exprNode, env *exprEnv) (float64, error) {
+	switch n := node.(type) {
+	case *numberNode:
+		return n.value, nil
+	case *questionNode:
+		return env.question(n.index).value, nil
+	case *variableNode:
+		value, ok := env.variables[n.name]
+		if !ok {
+			return 0, fmt.Errorf("variable %s is undefined", n.name)
+		}
+		return value, nil
+	case *unaryNode:
+		value, err := evalNode(n.operand, env)
+		if err != nil {
+			return 0, err
+		}
+		if n.op == "not" {
+			return boolValue(value == 0), nil
+		}
+		return -value, nil
+	case *binaryNode:
+		return evalBinary(n, env)
+	case *callNode:
+		return evalCall(n, env)
+	}
+	return 0, errors.New("invalid expression")
+}
+
+func evalBinary(n *binaryNode, env *exprEnv) (float64, error) {
+	left, err := evalNode(n.left, env)
+	if err != nil {
+		return 0, err
+	}
+	// 逻辑运算短路求值
+	if n.op == "and" && left == 0 {
+		return 0, nil
+	}
+	if n.op == "or" && left != 0 {
+		return 1, nil
+	}
+	right, err := evalNode(n.right, env)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return left / right, nil
+	case "==":
+		return boolValue(left == right), nil
+	case "!=":
+		return boolValue(left != right), nil
+	case "<":
+		return boolValue(left < right), nil
+	case "<=":
+		return boolValue(left <= right), nil
+	case ">":
+		return boolValue(left > right), nil
+	case ">=":
+		return boolValue(left >= right), nil
+	case "and", "or":
+		return boolValue(right != 0), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func evalCall(n *callNode, env *exprEnv) (float64, error) {
+	switch n.name {
+	case "if":
+		condition, err := evalNode(n.args[0], env)
+		if err != nil {
+			return 0, err
+		}
+		if condition != 0 {
+			return evalNode(n.args[1], env)
+		}
+		return evalNode(n.args[2], env)
+	case "answered":
+		return boolValue(env.question(n.args[0].(*questionNode).index).answered), nil
+	case "abs":
+		value, err := evalNode(n.args[0], env)
+		return math.Abs(value), err
+	case "round":
+		value, err := evalNode(n.args[0], env)
+		if err != nil {
+			return 0, err
+		}
+		digits := 0.0
+		if len(n.args) > 1 {
+			if digits, err = evalNode(n.args[1], env); err != nil {
+				return 0, err
+			}
+		}
+		scale := math.Pow(10, math.Max(0, math.Min(10, math.Trunc(digits))))
+		return math.Round(value*scale) / scale, nil
+	}
+
+	// 聚合函数只统计已作答的题目
+	values := []float64{}
+	for _, arg := range n.args {
+		switch a := arg.(type) {
+		case *rangeNode:
+			for i := a.from; i <= a.to && i < len(env.questions); i++ {
+				if question := env.question(i); question.answered {
+					values = append(values, question.value)
+				}
+			}
+		case *questionNode:
+			if question := env.question(a.index); question.answered {
+				values = append(values, question.value)
+			}
+		default:
+			value, err := evalNode(arg, env)
+			if err != nil {
+				return 0, err
+			}
+			values = append(values, value)
+		}
+	}
+
+	switch n.name {
+	case "count":
+		return float64(len(values)), nil
+	case "sum", "avg":
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		if n.name == "sum" {
+			return sum, nil
+		}
+		if len(values) == 0 {
+			return 0, errors.New("avg of no values")
+		}
+		return sum / float64(len(values)), nil
+	case "min", "max":
+		if len(values) == 0 {
+			return 0, fmt.Errorf("%s of no values", n.name)
+		}
+		result := values[0]
+		for _, value := range values[1:] {
+			if n.name == "min" {
+				result = math.Min(result, value)
+			} else {
+				result = math.Max(result, value)
+			}
+		}
+		return result, nil
+	}
+	return 0, fmt.Errorf("unknown function %s", n.name)
+}
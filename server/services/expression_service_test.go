@@ -0,0 +1,133 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpressionEval(t *testing.T) {
+	env := &exprEnv{
+		questions: []questionValue{
+			{value: 3, answered: true},
+			{value: 5, answered: true},
+			{},
+			{value: 2, answered: true},
+		},
+		variables: map[string]float64{"base": 10},
+	}
+
+	tests := []struct {
+		source string
+		want   float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-Q1 + base", 7},
+		{"10 / 4", 2.5},
+		{"Q1 < Q2", 1},
+		{"Q1 >= Q2", 0},
+		{"Q1 == 3 and Q2 == 5", 1},
+		{"Q1 == 4 or not Q3", 1},
+		{"sum(Q1:Q4)", 10},
+		{"count(Q1:Q4)", 3},
+		{"avg(Q1, Q2)", 4},
+		{"min(Q1:Q4)", 2},
+		{"max(Q1:Q4, 7)", 7},
+		{"sum(Q3:Q100)", 2},
+		{"answered(Q3)", 0},
+		{"answered(Q4)", 1},
+		{"if(Q1 > 2, 100, 200)", 100},
+		{"abs(0 - 4)", 4},
+		{"round(10 / 3, 2)", 3.33},
+		{"round(2.5)", 3},
+		// 短路求值时右侧不会执行
+		{"0 and 1 / 0", 0},
+		{"1 or 1 / 0", 1},
+	}
+	for _, tt := range tests {
+		expr, err := parseExpression(tt.source)
+		if err != nil {
+			t.Errorf("parseExpression(%q): %v", tt.source, err)
+			continue
+		}
+		got, err := expr.eval(env)
+		if err != nil {
+			t.Errorf("eval(%q): %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("eval(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestExpressionEvalErrors(t *testing.T) {
+	env := &exprEnv{questions: []questionValue{{}}, variables: map[string]float64{}}
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"1 / 0", "division by zero"},
+		{"missing + 1", "variable missing is undefined"},
+		{"avg(Q1:Q1)", "avg of no values"},
+	}
+	for _, tt := range tests {
+		expr, err := parseExpression(tt.source)
+		if err != nil {
+			t.Errorf("parseExpression(%q): %v", tt.source, err)
+			continue
+		}
+		if _, err := expr.eval(env); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("eval(%q) error = %v, want %q", tt.source, err, tt.err)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"", "expression is empty"},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", `expected ")"`},
+		{"1 $ 2", "unexpected character"},
+		{"Q0", "invalid question reference"},
+		{"Q4:Q2", `unexpected ":"`},
+		{"sum(Q4:Q2)", "invalid question range"},
+		{"abs(Q1:Q2)", `expected ")"`},
+		{"answered(1)", "answered expects a question reference"},
+		{"unknown(1)", "unknown function"},
+		{"abs(1, 2)", "wrong number of arguments"},
+		{"Q10001", "invalid question reference"},
+		{"sum(Q1:Q10001)", "invalid question reference"},
+		{strings.Repeat("(", maxExpressionDepth+1) + "1" + strings.Repeat(")", maxExpressionDepth+1), "nested too deeply"},
+		{strings.Repeat("1+", maxExpressionLength), "at most"},
+	}
+	for _, tt := range tests {
+		_, err := parseExpression(tt.source)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseExpression(%q) error = %v, want %q", tt.source, err, tt.err)
+		}
+	}
+}
+
+func TestParseExpressionReferences(t *testing.T) {
+	expr, err := parseExpression("Q2 + sum(Q4:Q6) + answered(Q9) + total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expr.questions[1] || len(expr.questions) != 1 {
+		t.Errorf("questions = %v, want only index 1", expr.questions)
+	}
+	if len(expr.ranges) != 1 || expr.ranges[0].from != 3 || expr.ranges[0].to != 5 {
+		t.Errorf("ranges = %v, want one range 3..5", expr.ranges)
+	}
+	if !expr.answered[8] {
+		t.Errorf("answered = %v, want index 8", expr.answered)
+	}
+	if !expr.variables["total"] {
+		t.Errorf("variables = %v, want total", expr.variables)
+	}
+}
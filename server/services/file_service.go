@@ -1 +1,277 @@
 package services
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"server/common"
+	"server/config"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FileURLPrefix 上传文件的访问地址前缀，文件地址为 FileURLPrefix + FileID，不随存储方式变化
+const FileURLPrefix = "/api/file/"
+
+// 允许上传的文件类型及其扩展名，类型按文件内容识别，不信任客户端声明的类型
+var uploadFileTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var (
+	ErrFileTooLarge       = errors.New("file is too large")
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed, only png, jpeg, gif and webp images are accepted")
+	ErrFileNotFound       = errors.New("file not found")
+)
+
+var fileStorage FileStorage
+var maxUploadSize int64
+var fileGCGrace time.Duration
+var fileGCInterval time.Duration
+
+// fileReferencePattern 匹配文本中引用的上传文件地址，可以是站内路径或带域名的完整地址
+var fileReferencePattern = regexp.MustCompile(regexp.QuoteMeta(FileURLPrefix) + `([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)
+
+// InitFileConfig 初始化文件上传配置和存储
+func InitFileConfig() {
+	uploadConfig := config.Config.Upload
+	maxUploadSize = uploadConfig.MaxSize
+	if maxUploadSize <= 0 {
+		maxUploadSize = 5 << 20
+	}
+	fileGCGrace = parseDurationOrDefault(uploadConfig.GCGrace, 24*time.Hour)
+	fileGCInterval = parseDurationOrDefault(uploadConfig.GCInterval, time.Hour)
+
+	switch uploadConfig.Storage {
+	case "", "local":
+		dir := uploadConfig.Dir
+		if dir == "" {
+			dir = "./uploads"
+		}
+		fileStorage = &LocalFileStorage{Dir: dir}
+	case "s3":
+		s3 := uploadConfig.S3
+		if s3.Endpoint == "" || s3.Bucket == "" {
+			panic("Invalid upload configuration: s3 endpoint and bucket are required")
+		}
+		fileStorage = &S3FileStorage{
+			Endpoint:  s3.Endpoint,
+			Region:    s3.Region,
+			Bucket:    s3.Bucket,
+			AccessKey: s3.AccessKey,
+			SecretKey: s3.SecretKey,
+			PathStyle: s3.PathStyle,
+			Client:    &http.Client{Timeout: 30 * time.Second},
+		}
+	default:
+		panic("Invalid upload storage in configuration: " + uploadConfig.Storage)
+	}
+}
+
+// MaxUploadSize 返回单个文件的大小上限（字节）
+func MaxUploadSize() int64 {
+	return maxUploadSize
+}
+
+// FileInfo 上传文件信息
+type FileInfo struct {
+	FileID      string    `json:"fileId"`
+	URL         string    `json:"url"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	CreateTime  time.Time `json:"createTime"`
+}
+
+// fileInfoOf 将文件记录转换为返回给前端的信息
+func fileInfoOf(file *common.UploadedFile) *FileInfo {
+	return &FileInfo{
+		FileID:      file.FileID,
+		URL:         FileURLPrefix + file.FileID,
+		Name:        file.Name,
+		ContentType: file.ContentType,
+		Size:        file.Size,
+		CreateTime:  file.CreateTime,
+	}
+}
+
+// UploadFile 校验并保存上传的文件，超过保留时长仍未被引用的文件会被清理
+func UploadFile(userID string, header *multipart.FileHeader) (*FileInfo, error) {
+	if header.Size > maxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+	src, err := header.Open()
+	if err != nil {
+		return nil, errors.New("failed to read uploaded file")
+	}
+	defer src.Close()
+
+	// 多读一个字节以发现超出上限的文件
+	content, err := io.ReadAll(io.LimitReader(src, maxUploadSize+1))
+	if err != nil {
+		return nil, errors.New("failed to read uploaded file")
+	}
+	if int64(len(content)) > maxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+	if len(content) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	contentType := http.DetectContentType(content)
+	extension, ok := uploadFileTypes[contentType]
+	if !ok {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	now := time.Now()
+	file := common.UploadedFile{
+		FileID:      uuid.New().String(),
+		UserID:      userID,
+		Name:        uploadFileName(header.Filename),
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		CreateTime:  now,
+	}
+	file.StorageKey = now.Format("2006/01/") + file.FileID + extension
+
+	if err := fileStorage.Put(file.StorageKey, content, contentType); err != nil {
+		log.Printf("Failed to store uploaded file %s: %v", file.StorageKey, err)
+		return nil, errors.New("failed to store file")
+	}
+	if err := common.DB.Create(&file).Error; err != nil {
+		fileStorage.Delete(file.StorageKey)
+		return nil, errors.New("failed to save file")
+	}
+	return fileInfoOf(&file), nil
+}
+
+// uploadFileName 去掉客户端文件名中的路径并限制长度
+func uploadFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name
+}
+
+// OpenFile 打开上传的文件，返回文件记录和内容，调用方负责关闭内容
+func OpenFile(fileID string) (*common.UploadedFile, io.ReadCloser, error) {
+	var file common.UploadedFile
+	err := common.DB.Where("FileID = ?", fileID).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, nil, errors.New("failed to retrieve file")
+	}
+
+	content, err := fileStorage.Open(file.StorageKey)
+	if errors.Is(err, ErrStoredFileNotFound) {
+		return nil, nil, ErrFileNotFound
+	}
+	if err != nil {
+		log.Printf("Failed to open stored file %s: %v", file.StorageKey, err)
+		return nil, nil, errors.New("failed to read file")
+	}
+	return &file, content, nil
+}
+
+// fileReferenceColumns 可能引用上传文件的字段。回收站中的问卷、版本快照、草稿、
+// 模板和题库题目中的引用同样计入，恢复或复用时图片仍然可用
+var fileReferenceColumns = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&common.Survey{}, []string{"PCBackgroundImage", "PCBannerImage", "Footer", "Description", "ShowContent", "DraftContent"}},
+	{&common.SurveyVersion{}, []string{"Snapshot"}},
+	{&common.Question{}, []string{"Image", "Title", "Description"}},
+	{&common.QuestionOption{}, []string{"Image", "OptionContent"}},
+	{&common.SurveyTranslation{}, []string{"Text"}},
+}
+
+// collectFileReferences 收集所有被引用的上传文件ID
+func collectFileReferences() (map[string]bool, error) {
+	referenced := map[string]bool{}
+	for _, source := range fileReferenceColumns {
+		for _, column := range source.columns {
+			var values []sql.NullString
+			err := common.DB.Unscoped().Model(source.model).
+				Where(column+" LIKE ?", "%"+FileURLPrefix+"%").
+				Pluck(column, &values).Error
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range values {
+				for _, match := range fileReferencePattern.FindAllStringSubmatch(value.String, -1) {
+					referenced[match[1]] = true
+				}
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// PurgeOrphanedFiles 删除超过保留时长且不再被引用的上传文件，返回删除的数量
+func PurgeOrphanedFiles() (int, error) {
+	var files []common.UploadedFile
+	if err := common.DB.Where("CreateTime < ?", time.Now().Add(-fileGCGrace)).Find(&files).Error; err != nil {
+		return 0, errors.New("failed to retrieve uploaded files")
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+
+	referenced, err := collectFileReferences()
+	if err != nil {
+		return 0, errors.New("failed to collect file references")
+	}
+
+	purged := 0
+	for _, file := range files {
+		if referenced[file.FileID] {
+			continue
+		}
+		// 先删除记录再删除存储，存储删除失败时只会留下无法访问的文件
+		if err := common.DB.Delete(&file).Error; err != nil {
+			log.Printf("Failed to delete file record %s: %v", file.FileID, err)
+			continue
+		}
+		if err := fileStorage.Delete(file.StorageKey); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", file.StorageKey, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartFileGCJob 启动定期清理未被引用文件的任务
+func StartFileGCJob() {
+	go func() {
+		ticker := time.NewTicker(fileGCInterval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeOrphanedFiles()
+			if err != nil {
+				log.Printf("File cleanup failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d orphaned files", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
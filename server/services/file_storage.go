@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrStoredFileNotFound 存储中不存在该文件
+var ErrStoredFileNotFound = errors.New("stored file not found")
+
+// FileStorage 上传文件的存储，key 为存储中的相对路径
+type FileStorage interface {
+	Put(key string, content []byte, contentType string) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// LocalFileStorage 将文件保存在本地目录中
+type LocalFileStorage struct {
+	Dir string
+}
+
+// path 返回 key 对应的本地路径，拒绝跳出存储目录的 key
+func (s *LocalFileStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
+
+// Put 先写入临时文件再重命名，避免读到写了一半的文件
+func (s *LocalFileStorage) Put(key string, content []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalFileStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrStoredFileNotFound
+	}
+	return file, err
+}
+
+// Delete 删除文件，文件不存在时不报错
+func (s *LocalFileStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// S3FileStorage 将文件保存在 S3 兼容的对象存储中，请求使用 AWS Signature V4 签名
+type S3FileStorage struct {
+	Endpoint  string // 如 https://s3.example.com
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // 是否使用 endpoint/bucket/key 形式的地址，MinIO 等通常需要开启
+	Client    *http.Client
+}
+
+// objectURL 返回对象的访问地址
+func (s *S3FileStorage) objectURL(key string) (*url.URL, error) {
+	endpoint, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, errors.New("invalid s3 endpoint")
+	}
+	if s.PathStyle {
+		endpoint.Path += "/" + s.Bucket + "/" + key
+	} else {
+		endpoint.Host = s.Bucket + "." + endpoint.Host
+		endpoint.Path += "/" + key
+	}
+	return endpoint, nil
+}
+
+// do 发送签名后的请求，返回 2xx 以外的状态码时报错
+func (s *S3FileStorage) do(method, key string, content []byte, contentType string) (*http.Response, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(method, target.String(), bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	s.sign(request, content, time.Now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrStoredFileNotFound
+	}
+	if response.StatusCode/100 != 2 {
+		response.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: unexpected status %d", method, key, response.StatusCode)
+	}
+	return response, nil
+}
+
+// sign 按 AWS Signature V4 为请求添加认证头
+func (s *S3FileStorage) sign(request *http.Request, content []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(content)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + request.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func (s *S3FileStorage) Put(key string, content []byte, contentType string) error {
+	response, err := s.do(http.MethodPut, key, content, contentType)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+func (s *S3FileStorage) Open(key string) (io.ReadCloser, error) {
+	response, err := s.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// Delete 删除对象，对象不存在时不报错
+func (s *S3FileStorage) Delete(key string) error {
+	response, err := s.do(http.MethodDelete, key, nil, "")
+	if errors.Is(err, ErrStoredFileNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"server/common"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FolderResponse 文件夹信息
+type FolderResponse struct {
+	FolderID    string    `json:"folderId"`
+	ParentID    string    `json:"parentId"`
+	Name        string    `json:"name"`
+	SurveyCount int64     `json:"surveyCount"`
+	CreateTime  time.Time `json:"createTime"`
+}
+
+// validateFolderName 校验文件夹名称
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return "", errors.New("folder name must be between 1 and 100 characters")
+	}
+	return name, nil
+}
+
+// getOwnedFolder 获取文件夹并校验其属于指定用户
+func getOwnedFolder(tx *gorm.DB, folderID, userID string) (*common.Folder, error) {
+	var folder common.Folder
+	if err := tx.Where("FolderID = ?", folderID).First(&folder).Error; err != nil {
+		return nil, errors.New("folder not found")
+	}
+	if folder.UserID != userID {
+		return nil, errors.New("permission denied")
+	}
+	return &folder, nil
+}
+
+// ListFolders 获取用户的全部文件夹，前端根据 parentId 组装树形结构
+func ListFolders(userID string) ([]FolderResponse, error) {
+	var folders []common.Folder
+	if err := common.DB.Where("UserID = ?", userID).Order("Name ASC").Find(&folders).Error; err != nil {
+		return nil, errors.New("failed to retrieve folders")
+	}
+
+	// 统计每个文件夹中的问卷数量
+	var counts []struct {
+		FolderID string `gorm:"column:FolderID"`
+		Total    int64  `gorm:"column:Total"`
+	}
+	if err := common.DB.Model(&common.Survey{}).
+		Select("FolderID, COUNT(*) AS Total").
+		Where("UserID = ? AND FolderID <> ''", userID).
+		Group("FolderID").
+		Scan(&counts).Error; err != nil {
+		return nil, errors.New("failed to count folder surveys")
+	}
+	countMap := map[string]int64{}
+	for _, count := range counts {
+		countMap[count.FolderID] = count.Total
+	}
+
+	responses := []FolderResponse{}
+	for _, folder := range folders {
+		responses = append(responses, FolderResponse{
+			FolderID:    folder.FolderID,
+			ParentID:    folder.ParentID,
+			Name:        folder.Name,
+			SurveyCount: countMap[folder.FolderID],
+			CreateTime:  folder.CreateTime,
+		})
+	}
+	return responses, nil
+}
+
+// CreateFolder 创建文件夹，parentID 为空时创建在根目录
+func CreateFolder(userID, name, parentID string) (*FolderResponse, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != "" {
+		if _, err := getOwnedFolder(common.DB, parentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	folder := common.Folder{
+		FolderID:   uuid.New().String(),
+		UserID:     userID,
+		ParentID:   parentID,
+		Name:       name,
+		CreateTime: time.Now(),
+	}
+	if err := common.DB.Create(&folder).Error; err != nil {
+		return nil, errors.New("failed to create folder")
+	}
+
+	return &FolderResponse{
+		FolderID:   folder.FolderID,
+		ParentID:   folder.ParentID,
+		Name:       folder.Name,
+		CreateTime: folder.CreateTime,
+	}, nil
+}
+
+// RenameFolder 重命名文件夹
+func RenameFolder(userID, folderID, name string) error {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return err
+	}
+	folder, err := getOwnedFolder(common.DB, folderID, userID)
+	if err != nil {
+		return err
+	}
+	if err := common.DB.Model(folder).Update("Name", name).Error; err != nil {
+		return errors.New("failed to rename folder")
+	}
+	return nil
+}
+
+// MoveFolder 将文件夹移动到新的父文件夹下，parentID 为空时移动到根目录
+func MoveFolder(userID, folderID, parentID string) error {
+	folder, err := getOwnedFolder(common.DB, folderID, userID)
+	if err != nil {
+		return err
+	}
+
+	// 检查目标文件夹不是自身或其子孙，避免形成环
+	for current := parentID; current != ""; {
+		if current == folderID {
+			return errors.New("cannot move a folder into itself or its subfolder")
+		}
+		parent, err := getOwnedFolder(common.DB, current, userID)
+		if err != nil {
+			return err
+		}
+		current = parent.ParentID
+	}
+
+	if err := common.DB.Model(folder).Update("ParentID", parentID).Error; err != nil {
+		return errors.New("failed to move folder")
+	}
+	return nil
+}
+
+// collectSubfolderIDs 收集文件夹及其所有子孙文件夹的 ID
+func collectSubfolderIDs(tx *gorm.DB, folderID string) ([]string, error) {
+	folderIDs := []string{folderID}
+	for i := 0; i < len(folderIDs); i++ {
+		var children []string
+		if err := tx.Model(&common.Folder{}).Where("ParentID = ?", folderIDs[i]).Pluck("FolderID", &children).Error; err != nil {
+			return nil, err
+		}
+		folderIDs = append(folderIDs, children...)
+	}
+	return folderIDs, nil
+}
+
+// DeleteFolder 删除文件夹及其子文件夹，其中的问卷移动到根目录
+func DeleteFolder(userID, folderID string) error {
+	if _, err := getOwnedFolder(common.DB, folderID, userID); err != nil {
+		return err
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		folderIDs, err := collectSubfolderIDs(tx, folderID)
+		if err != nil {
+			return errors.New("failed to collect subfolders")
+		}
+
+		// 问卷（包括回收站中的问卷）移动到根目录
+		if err := tx.Unscoped().Model(&common.Survey{}).
+			Where("UserID = ? AND FolderID IN ?", userID, folderIDs).
+			Update("FolderID", "").Error; err != nil {
+			return errors.New("failed to move surveys to root")
+		}
+
+		if err := tx.Where("FolderID IN ?", folderIDs).Delete(&common.Folder{}).Error; err != nil {
+			return errors.New("failed to delete folder")
+		}
+		return nil
+	})
+}
+
+// MoveSurveys 批量移动问卷到文件夹，folderID 为空时移动到根目录
+func MoveSurveys(userID string, surveyIDs []string, folderID string) error {
+	// 去除重复 ID
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, surveyID := range surveyIDs {
+		if surveyID != "" && !seen[surveyID] {
+			seen[surveyID] = true
+			ids = append(ids, surveyID)
+		}
+	}
+	if len(ids) == 0 {
+		return errors.New("surveyIds is required")
+	}
+	if folderID != "" {
+		if _, err := getOwnedFolder(common.DB, folderID, userID); err != nil {
+			return err
+		}
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		// 先确认问卷均存在且属于该用户，RowsAffected 不包含值未变化的行
+		var count int64
+		err := tx.Model(&common.Survey{}).
+			Where("UserID = ? AND SurveyID IN ?", userID, ids).
+			Count(&count).Error
+		if err != nil {
+			return errors.New("failed to move surveys")
+		}
+		if count != int64(len(ids)) {
+			return errors.New("some surveys were not found")
+		}
+
+		err = tx.Model(&common.Survey{}).
+			Where("UserID = ? AND SurveyID IN ?", userID, ids).
+			Update("FolderID", folderID).Error
+		if err != nil {
+			return errors.New("failed to move surveys")
+		}
+		return nil
+	})
+}
+
+// normalizeTags 去除空白与重复标签
+func normalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > 50 {
+			return nil, errors.New("tag must be at most 50 characters")
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("tags is required")
+	}
+	return result, nil
+}
+
+// TagSurvey 为问卷添加标签
+func TagSurvey(userID, surveyID string, tags []string) error {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return err
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		for _, tag := range tags {
+			surveyTag := common.SurveyTag{SurveyID: surveyID, Tag: tag, UserID: userID}
+			if err := tx.Where(surveyTag).FirstOrCreate(&surveyTag).Error; err != nil {
+				return errors.New("failed to tag survey")
+			}
+		}
+		return nil
+	})
+}
+
+// UntagSurvey 移除问卷的标签
+func UntagSurvey(userID, surveyID string, tags []string) error {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return err
+	}
+
+	if err := common.DB.Where("SurveyID = ? AND Tag IN ?", surveyID, tags).Delete(&common.SurveyTag{}).Error; err != nil {
+		return errors.New("failed to untag survey")
+	}
+	return nil
+}
+
+// ListTags 获取用户使用过的所有标签
+func ListTags(userID string) ([]string, error) {
+	tags := []string{}
+	if err := common.DB.Model(&common.SurveyTag{}).
+		Where("UserID = ?", userID).
+		Distinct("Tag").
+		Order("Tag ASC").
+		Pluck("Tag", &tags).Error; err != nil {
+		return nil, errors.New("failed to retrieve tags")
+	}
+	return tags, nil
+}
+
+// getSurveyTags 批量获取问卷的标签
+func getSurveyTags(surveyIDs []string) (map[string][]string, error) {
+	result := map[string][]string{}
+	if len(surveyIDs) == 0 {
+		return result, nil
+	}
+	var surveyTags []common.SurveyTag
+	if err := common.DB.Where("SurveyID IN ?", surveyIDs).Order("Tag ASC").Find(&surveyTags).Error; err != nil {
+		return nil, err
+	}
+	for _, surveyTag := range surveyTags {
+		result[surveyTag.SurveyID] = append(result[surveyTag.SurveyID], surveyTag.Tag)
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pipingPattern 引用前面题目答案的占位符，{{Q3}} 表示第 3 题（按编辑时的题目顺序）的答案
+var pipingPattern = regexp.MustCompile(`\{\{\s*[Qq](\d+)\s*\}\}`)
+
+// RenderedQuestion 替换占位符后答题者看到的题目文本
+type RenderedQuestion struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Options     map[string]string `json:"options,omitempty"` // 选项 ID 到选项内容
+}
+
+// validatePipingPlaceholders 检查占位符只引用当前题目之前的题目
+func validatePipingPlaceholders(prefix string, index int, question QuestionModel) []string {
+	errs := []string{}
+	check := func(field, text string) {
+		for _, match := range pipingPattern.FindAllStringSubmatch(text, -1) {
+			n, _ := strconv.Atoi(match[1])
+			if n < 1 || n > index {
+				errs = append(errs, fmt.Sprintf("%s.%s: placeholder %s must reference an earlier question", prefix, field, match[0]))
+			}
+		}
+	}
+	check("Title", question.Title)
+	check("Description", question.Description)
+	for j, option := range question.Options {
+		check(fmt.Sprintf("Options[%d].OptionContent", j), option.OptionContent)
+	}
+	return errs
+}
+
+// pipedAnswerText 将题目的答案转换为插入到文本中的内容
+func pipedAnswerText(question QuestionModel, answer *QuestionResponseModel) string {
+	if answer == nil {
+		return ""
+	}
+	values := []string{}
+	switch question.Type {
+	case "SingleChoice", "MultiChoice":
+		selected := map[string]string{}
+		for _, option := range answer.Options {
+			if option.IsSelect {
+				selected[option.OptionID] = strings.TrimSpace(option.OtherText)
+			}
+		}
+		for _, option := range question.Options {
+			otherText, ok := selected[option.OptionID]
+			if !ok {
+				continue
+			}
+			if option.IsOpenEnded && otherText != "" {
+				values = append(values, otherText)
+			} else {
+				values = append(values, option.OptionContent)
+			}
+		}
+	case "SingleTextFillIn", "MultiTextFillIn":
+		for _, textFillIn := range answer.TextFillIns {
+			if content := strings.TrimSpace(textFillIn.TextContent); content != "" {
+				values = append(values, content)
+			}
+		}
+	case "SingleNumFillIn", "MultiNumFillIn":
+		for _, numFillIn := range answer.NumFillIns {
+			values = append(values, strconv.FormatFloat(numFillIn.NumContent, 'f', -1, 64))
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// renderPipedText 用已作答的内容替换题目标题、描述和选项中的占位符，questions 须为编辑时的完整题目列表。
+// 未作答或引用无效时替换为空，hidden 中的题目不做替换，返回发生替换的可见题目的最终文本
+func renderPipedText(questions []QuestionModel, answers []QuestionResponseModel, hidden map[string]bool) map[string]RenderedQuestion {
+	answerMap := map[string]*QuestionResponseModel{}
+	for i := range answers {
+		answerMap[answers[i].QID] = &answers[i]
+	}
+
+	rendered := map[string]RenderedQuestion{}
+	for i := range questions {
+		question := &questions[i]
+		if hidden[question.QuestionID] {
+			continue
+		}
+		changed := false
+		replace := func(text string) string {
+			if !strings.Contains(text, "{{") {
+				return text
+			}
+			return pipingPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+				changed = true
+				n, _ := strconv.Atoi(pipingPattern.FindStringSubmatch(placeholder)[1])
+				if n < 1 || n > i {
+					return ""
+				}
+				source := questions[n-1]
+				return pipedAnswerText(source, answerMap[source.QuestionID])
+			})
+		}
+
+		question.Title = replace(question.Title)
+		question.Description = replace(question.Description)
+		options := map[string]string{}
+		for j := range question.Options {
+			option := &question.Options[j]
+			option.OptionContent = replace(option.OptionContent)
+			options[option.OptionID] = option.OptionContent
+		}
+		if !changed {
+			continue
+		}
+		result := RenderedQuestion{Title: question.Title, Description: question.Description}
+		if len(options) > 0 {
+			result.Options = options
+		}
+		rendered[question.QuestionID] = result
+	}
+	return rendered
+}
+
+// encodeRenderedText 将替换后的文本编码为 JSON 保存，没有占位符时返回空
+func encodeRenderedText(rendered map[string]RenderedQuestion) string {
+	if len(rendered) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// decodeRenderedText 解析答卷保存的最终文本
+func decodeRenderedText(data string) map[string]RenderedQuestion {
+	rendered := map[string]RenderedQuestion{}
+	if data != "" {
+		json.Unmarshal([]byte(data), &rendered)
+	}
+	return rendered
+}
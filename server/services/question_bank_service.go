@@ -0,0 +1,415 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"server/common"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BankQuestionResponse 题库题目信息
+type BankQuestionResponse struct {
+	BankQuestionID string        `json:"bankQuestionId"`
+	Title          string        `json:"title"`
+	QuestionType   string        `json:"questionType"`
+	Tags           []string      `json:"tags"`
+	CreateTime     time.Time     `json:"createTime"`
+	Question       QuestionModel `json:"question"`
+}
+
+// BankQuestionUsage 题库题目在某个问卷中的作答统计
+type BankQuestionUsage struct {
+	SurveyID      string           `json:"surveyId"`
+	SurveyTitle   string           `json:"surveyTitle"`
+	QuestionID    string           `json:"questionId"`
+	ResponseCount int64            `json:"responseCount"`
+	OptionCounts  map[string]int64 `json:"optionCounts,omitempty"` // 按选项内容统计的选择次数
+	TextCount     int64            `json:"textCount,omitempty"`    // 文本填空回答数
+	NumStats      *NumFillInStats  `json:"numStats,omitempty"`     // 数字填空统计
+}
+
+// NumFillInStats 数字填空统计
+type NumFillInStats struct {
+	Count   int64   `json:"count"`
+	Average float64 `json:"average"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+}
+
+// BankQuestionStats 题库题目跨问卷的作答对比
+type BankQuestionStats struct {
+	BankQuestionID string              `json:"bankQuestionId"`
+	Title          string              `json:"title"`
+	QuestionType   string              `json:"questionType"`
+	Surveys        []BankQuestionUsage `json:"surveys"`
+	OptionTotals   map[string]int64    `json:"optionTotals,omitempty"` // 所有问卷合计的选项选择次数
+}
+
+// getOwnedBankQuestion 获取用户题库中的题目
+func getOwnedBankQuestion(bankQuestionID, userID string) (*common.BankQuestion, error) {
+	var bankQuestion common.BankQuestion
+	if err := common.DB.Where("BankQuestionID = ?", bankQuestionID).First(&bankQuestion).Error; err != nil {
+		return nil, errors.New("bank question not found")
+	}
+	if bankQuestion.UserID != userID {
+		return nil, errors.New("permission denied")
+	}
+	return &bankQuestion, nil
+}
+
+// getBankQuestionTags 获取题库题目的标签
+func getBankQuestionTags(bankQuestionIDs []string) (map[string][]string, error) {
+	result := map[string][]string{}
+	if len(bankQuestionIDs) == 0 {
+		return result, nil
+	}
+	var tags []common.BankQuestionTag
+	if err := common.DB.Where("BankQuestionID IN ?", bankQuestionIDs).Order("Tag").Find(&tags).Error; err != nil {
+		return nil, errors.New("failed to retrieve bank question tags")
+	}
+	for _, tag := range tags {
+		result[tag.BankQuestionID] = append(result[tag.BankQuestionID], tag.Tag)
+	}
+	return result, nil
+}
+
+// ListBankQuestions 搜索用户题库，可按标题关键字和标签筛选
+func ListBankQuestions(userID, keyword, tag string) ([]BankQuestionResponse, error) {
+	db := common.DB.Where("UserID = ?", userID)
+	if keyword = strings.TrimSpace(keyword); keyword != "" {
+		db = db.Where(`Title LIKE ? ESCAPE '\\'`, "%"+escapeLike(keyword)+"%")
+	}
+	if tag = strings.TrimSpace(tag); tag != "" {
+		db = db.Where("BankQuestionID IN (?)", common.DB.Model(&common.BankQuestionTag{}).
+			Select("BankQuestionID").Where("UserID = ? AND Tag = ?", userID, tag))
+	}
+
+	var bankQuestions []common.BankQuestion
+	if err := db.Order("CreateTime DESC").Find(&bankQuestions).Error; err != nil {
+		return nil, errors.New("failed to retrieve bank questions")
+	}
+
+	ids := []string{}
+	for _, bankQuestion := range bankQuestions {
+		ids = append(ids, bankQuestion.BankQuestionID)
+	}
+	tags, err := getBankQuestionTags(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []BankQuestionResponse{}
+	for _, bankQuestion := range bankQuestions {
+		questions, err := loadQuestionModels(common.DB, bankQuestion.BankQuestionID, bankQuestion.QuestionID)
+		if err != nil || len(questions) == 0 {
+			return nil, errors.New("failed to load bank question: " + bankQuestion.BankQuestionID)
+		}
+		questionTags := tags[bankQuestion.BankQuestionID]
+		if questionTags == nil {
+			questionTags = []string{}
+		}
+		responses = append(responses, BankQuestionResponse{
+			BankQuestionID: bankQuestion.BankQuestionID,
+			Title:          bankQuestion.Title,
+			QuestionType:   bankQuestion.QuestionType,
+			Tags:           questionTags,
+			CreateTime:     bankQuestion.CreateTime,
+			Question:       questions[0],
+		})
+	}
+	return responses, nil
+}
+
+// ListBankTags 获取用户题库中使用过的标签
+func ListBankTags(userID string) ([]string, error) {
+	tags := []string{}
+	err := common.DB.Model(&common.BankQuestionTag{}).
+		Where("UserID = ?", userID).
+		Distinct("Tag").
+		Order("Tag").
+		Pluck("Tag", &tags).Error
+	if err != nil {
+		return nil, errors.New("failed to retrieve tags")
+	}
+	return tags, nil
+}
+
+// SaveQuestionToBank 将问卷中的题目连同选项和填空保存到题库，返回题库题目 ID。
+// 题目从问卷当前编辑内容中读取，包含未发布的草稿。关联题库会修改问卷内容，同时返回新的修订号
+func SaveQuestionToBank(userID, surveyID, questionID string, tags []string) (string, int, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(tags) > 0 {
+		if tags, err = normalizeTags(tags); err != nil {
+			return "", 0, err
+		}
+	}
+
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return "", 0, err
+	}
+	var question *QuestionModel
+	for i := range model.Questions {
+		if model.Questions[i].QuestionID == questionID {
+			question = &model.Questions[i]
+			break
+		}
+	}
+	if question == nil {
+		return "", 0, errors.New("question not found")
+	}
+
+	// 题库保存的是副本，使用新的 ID
+	copied := []QuestionModel{*question}
+	assignFreshIDs(copied)
+	copied[0].BankQuestionID = ""
+	// 显示条件按题号引用所在问卷的题目，离开原问卷后没有意义
+	copied[0].DisplayCondition = ""
+
+	bankQuestion := common.BankQuestion{
+		BankQuestionID: uuid.New().String(),
+		UserID:         userID,
+		QuestionID:     copied[0].QuestionID,
+		Title:          copied[0].Title,
+		QuestionType:   copied[0].Type,
+		CreateTime:     time.Now(),
+	}
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		// 关联题库会修改问卷，递增修订号让持有旧 ETag 的编辑者感知变化
+		if err := touchSurvey(tx, survey, userID, -1); err != nil {
+			return err
+		}
+		if _, err := createQuestions(tx, bankQuestion.BankQuestionID, copied); err != nil {
+			return err
+		}
+		if err := tx.Create(&bankQuestion).Error; err != nil {
+			return errors.New("failed to save bank question")
+		}
+		for _, tag := range tags {
+			bankTag := common.BankQuestionTag{BankQuestionID: bankQuestion.BankQuestionID, Tag: tag, UserID: userID}
+			if err := tx.Create(&bankTag).Error; err != nil {
+				return errors.New("failed to tag bank question")
+			}
+		}
+
+		// 已发布的源题目关联到题库，便于跨问卷对比
+		err := tx.Model(&common.Question{}).
+			Where("QuestionID = ? AND SurveyID = ? AND BankQuestionID = ?", questionID, surveyID, "").
+			Update("BankQuestionID", bankQuestion.BankQuestionID).Error
+		if err != nil {
+			return errors.New("failed to link question to bank")
+		}
+
+		// 存在草稿时同步关联，避免发布后丢失。在锁住问卷行后重新读取草稿，只修改这一道题的关联
+		var current common.Survey
+		if err := tx.Select("DraftContent").Where("SurveyID = ?", surveyID).First(&current).Error; err != nil {
+			return errors.New("survey not found")
+		}
+		if current.DraftContent == "" {
+			return nil
+		}
+		var draft SurveyModel
+		if err := json.Unmarshal([]byte(current.DraftContent), &draft); err != nil {
+			return errors.New("failed to decode survey draft")
+		}
+		linked := false
+		for i := range draft.Questions {
+			if draft.Questions[i].QuestionID == questionID && draft.Questions[i].BankQuestionID == "" {
+				draft.Questions[i].BankQuestionID = bankQuestion.BankQuestionID
+				linked = true
+			}
+		}
+		if !linked {
+			return nil
+		}
+		content, err := json.Marshal(draft)
+		if err != nil {
+			return errors.New("failed to encode survey draft")
+		}
+		if err := tx.Model(survey).Update("DraftContent", string(content)).Error; err != nil {
+			return errors.New("failed to link question to bank")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return bankQuestion.BankQuestionID, survey.Revision, nil
+}
+
+// SetBankQuestionTags 替换题库题目的标签，tags 为空时清除所有标签
+func SetBankQuestionTags(userID, bankQuestionID string, tags []string) error {
+	if _, err := getOwnedBankQuestion(bankQuestionID, userID); err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		var err error
+		if tags, err = normalizeTags(tags); err != nil {
+			return err
+		}
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("BankQuestionID = ?", bankQuestionID).Delete(&common.BankQuestionTag{}).Error; err != nil {
+			return errors.New("failed to update tags")
+		}
+		for _, tag := range tags {
+			bankTag := common.BankQuestionTag{BankQuestionID: bankQuestionID, Tag: tag, UserID: userID}
+			if err := tx.Create(&bankTag).Error; err != nil {
+				return errors.New("failed to update tags")
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteBankQuestion 删除题库题目，已插入问卷的副本不受影响
+func DeleteBankQuestion(userID, bankQuestionID string) error {
+	if _, err := getOwnedBankQuestion(bankQuestionID, userID); err != nil {
+		return err
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteQuestions(tx, bankQuestionID); err != nil {
+			return err
+		}
+		if err := tx.Where("BankQuestionID = ?", bankQuestionID).Delete(&common.BankQuestionTag{}).Error; err != nil {
+			return errors.New("failed to delete bank question")
+		}
+		if err := tx.Where("BankQuestionID = ?", bankQuestionID).Delete(&common.BankQuestion{}).Error; err != nil {
+			return errors.New("failed to delete bank question")
+		}
+		return nil
+	})
+}
+
+// InsertBankQuestions 将题库题目的副本追加到问卷草稿末尾，返回保存后的修订号
+func InsertBankQuestions(surveyID, userID string, expectedRevision int, bankQuestionIDs []string) (int, error) {
+	if len(bankQuestionIDs) == 0 {
+		return 0, errors.New("bankQuestionIds is required")
+	}
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return 0, err
+	}
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, bankQuestionID := range bankQuestionIDs {
+		bankQuestion, err := getOwnedBankQuestion(bankQuestionID, userID)
+		if err != nil {
+			return 0, err
+		}
+		questions, err := loadQuestionModels(common.DB, bankQuestion.BankQuestionID, bankQuestion.QuestionID)
+		if err != nil {
+			return 0, err
+		}
+		assignFreshIDs(questions)
+		for i := range questions {
+			questions[i].BankQuestionID = bankQuestion.BankQuestionID
+		}
+		model.Questions = append(model.Questions, questions...)
+	}
+
+	return SaveSurveyEditService(surveyID, userID, expectedRevision, model)
+}
+
+// GetBankQuestionStats 对比同一题库题目在各问卷中的作答情况，只统计已发布的题目
+func GetBankQuestionStats(userID, bankQuestionID string) (*BankQuestionStats, error) {
+	bankQuestion, err := getOwnedBankQuestion(bankQuestionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var usages []struct {
+		QuestionID   string
+		SurveyID     string
+		SurveyTitle  string
+		QuestionType string
+	}
+	err = common.DB.Table("questions AS q").
+		Select("q.QuestionID AS question_id, q.SurveyID AS survey_id, s.Title AS survey_title, q.QuestionType AS question_type").
+		Joins("JOIN surveys AS s ON s.SurveyID = q.SurveyID").
+		Where("q.BankQuestionID = ? AND s.UserID = ? AND s.DeletedAt IS NULL", bankQuestionID, userID).
+		Order("s.CreateTime").
+		Scan(&usages).Error
+	if err != nil {
+		return nil, errors.New("failed to retrieve bank question usage")
+	}
+
+	stats := &BankQuestionStats{
+		BankQuestionID: bankQuestion.BankQuestionID,
+		Title:          bankQuestion.Title,
+		QuestionType:   bankQuestion.QuestionType,
+		Surveys:        []BankQuestionUsage{},
+	}
+	for _, usage := range usages {
+		item := BankQuestionUsage{
+			SurveyID:    usage.SurveyID,
+			SurveyTitle: usage.SurveyTitle,
+			QuestionID:  usage.QuestionID,
+		}
+		// 有任意选中选项或填空回答的答卷计为作答
+		err := common.DB.Raw(`SELECT COUNT(DISTINCT ResponseID) FROM (
+			SELECT ResponseID FROM response_options WHERE QuestionID = ? AND IsSelect = ?
+			UNION ALL SELECT ResponseID FROM response_text_fill_ins WHERE QuestionID = ? AND TextContent <> ''
+			UNION ALL SELECT ResponseID FROM response_num_fill_ins WHERE QuestionID = ?
+		) AS answered`, usage.QuestionID, true, usage.QuestionID, usage.QuestionID).Scan(&item.ResponseCount).Error
+		if err != nil {
+			return nil, errors.New("failed to count responses")
+		}
+
+		switch usage.QuestionType {
+		case "SingleChoice", "MultiChoice":
+			// 不同问卷中的选项 ID 不同，按选项内容对齐
+			var counts []struct {
+				OptionContent string
+				Total         int64
+			}
+			err := common.DB.Table("response_options AS r").
+				Select("o.OptionContent AS option_content, COUNT(*) AS total").
+				Joins("JOIN question_options AS o ON o.OptionID = r.OptionID").
+				Where("r.QuestionID = ? AND r.IsSelect = ?", usage.QuestionID, true).
+				Group("o.OptionContent").
+				Scan(&counts).Error
+			if err != nil {
+				return nil, errors.New("failed to count option selections")
+			}
+			item.OptionCounts = map[string]int64{}
+			if stats.OptionTotals == nil {
+				stats.OptionTotals = map[string]int64{}
+			}
+			for _, count := range counts {
+				item.OptionCounts[count.OptionContent] = count.Total
+				stats.OptionTotals[count.OptionContent] += count.Total
+			}
+		case "SingleTextFillIn", "MultiTextFillIn":
+			if err := common.DB.Model(&common.ResponseTextFillIn{}).
+				Where("QuestionID = ? AND TextContent <> ?", usage.QuestionID, "").
+				Count(&item.TextCount).Error; err != nil {
+				return nil, errors.New("failed to count text answers")
+			}
+		case "SingleNumFillIn", "MultiNumFillIn":
+			var numStats NumFillInStats
+			err := common.DB.Model(&common.ResponseNumFillIn{}).
+				Select("COUNT(*) AS count, COALESCE(AVG(NumContent), 0) AS average, COALESCE(MIN(NumContent), 0) AS min, COALESCE(MAX(NumContent), 0) AS max").
+				Where("QuestionID = ?", usage.QuestionID).
+				Scan(&numStats).Error
+			if err != nil {
+				return nil, errors.New("failed to compute numeric statistics")
+			}
+			item.NumStats = &numStats
+		}
+		stats.Surveys = append(stats.Surveys, item)
+	}
+	return stats, nil
+}
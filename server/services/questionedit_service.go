@@ -1,45 +1,66 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"server/common"
 	"strings"
+
+	"gorm.io/gorm"
 )
 
 // SurveyMetaModel 定义符合 API 文档的响应结构
 type SurveyMetaModel struct {
-	SurveyID       string `json:"surveyId"`
-	AccessID       string `json:"accessId"`
-	UserID         string `json:"userId"`
-	Title          string `json:"title"`
-	Description    string `json:"description"`
-	CreateTime     string `json:"createTime"`
-	LastUpdateTime string `json:"lastUpdateTime"`
-	LastUpdateUser string `json:"lastUpdateUser"`
-	Status         string `json:"status"`
+	SurveyID         string `json:"surveyId"`
+	AccessID         string `json:"accessId"`
+	UserID           string `json:"userId"`
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	CreateTime       string `json:"createTime"`
+	LastUpdateTime   string `json:"lastUpdateTime"`
+	LastUpdateUser   string `json:"lastUpdateUser"`
+	Status           string `json:"status"`
+	Revision         int    `json:"revision"`
+	HasDraft         bool   `json:"hasDraft"`
+	Version          int    `json:"version"`
+	PublishedVersion int    `json:"publishedVersion"`
+
+	Appearance SurveyAppearance `json:"appearance"` // 外观设置
+	Settings   SurveyBehavior   `json:"settings"`   // 答题设置
 }
 
 // GetSurveyMetaService 获取问卷元数据
-func GetSurveyMetaService(surveyId string) (*SurveyMetaModel, error) {
-	var survey common.Survey
-
-	// 查询数据库获取问卷
-	err := common.DB.Where("SurveyID = ?", surveyId).First(&survey).Error
+func GetSurveyMetaService(surveyId, userID string) (*SurveyMetaModel, error) {
+	// 仅问卷所有者可以读取编辑信息
+	survey, err := getOwnedSurvey(surveyId, userID)
 	if err != nil {
-		return nil, errors.New("survey not found")
+		return nil, err
+	}
+
+	// 查询最后修改人
+	lastUpdateUser := survey.LastUpdateUser
+	var user common.User
+	if err := common.DB.Where("UserID = ?", survey.LastUpdateUser).First(&user).Error; err == nil {
+		lastUpdateUser = user.UserName
 	}
 
 	// 构造元数据响应
 	meta := &SurveyMetaModel{
-		SurveyID:       survey.SurveyID,
-		AccessID:       survey.AccessID,
-		UserID:         survey.UserID,
-		Title:          survey.Title,
-		Description:    survey.Description,
-		CreateTime:     survey.CreateTime.String(),
-		LastUpdateTime: survey.LastUpdateTime.String(),
-		LastUpdateUser: "admin", // 假设使用固定管理员作为示例
-		Status:         survey.Status,
+		SurveyID:         survey.SurveyID,
+		AccessID:         survey.AccessID,
+		UserID:           survey.UserID,
+		Title:            survey.Title,
+		Description:      survey.Description,
+		CreateTime:       survey.CreateTime.String(),
+		LastUpdateTime:   survey.LastUpdateTime.String(),
+		LastUpdateUser:   lastUpdateUser,
+		Status:           NormalizeStatus(survey.Status),
+		Revision:         survey.Revision,
+		HasDraft:         survey.DraftContent != "",
+		Version:          survey.CurrentVersion,
+		PublishedVersion: survey.PublishedVersion,
+		Appearance:       appearanceOf(survey),
+		Settings:         behaviorOf(survey),
 	}
 
 	return meta, nil
@@ -134,165 +155,258 @@ type TextFillIn struct {
 // 	}, nil
 // }
 
-// GetRespondentQuestionsController 获取问卷及问题
-func GetSurveyQuestionsService(surveyId string) (*SurveyModel, error) {
-	var survey common.Survey
-
-	// 查询 Survey
-	err := common.DB.Where("SurveyID = ?", surveyId).First(&survey).Error
+// GetSurveyQuestionsService 获取编辑中的问卷题目，存在草稿时返回草稿，否则返回已发布的题目
+func GetSurveyQuestionsService(surveyId, userID string) (*SurveyModel, error) {
+	// 编辑数据包含测验答案，仅问卷所有者可以读取
+	survey, err := getOwnedSurvey(surveyId, userID)
 	if err != nil {
-		return nil, errors.New("survey not found")
+		return nil, err
 	}
 
-	// 将 QuestionIDs 转换为问题 ID 的数组
-	questionIDArray := strings.Split(survey.QuestionIDs, ",")
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return nil, err
+	}
+	model.IsOpening = IsSurveyOpen(survey)
+	model.Revision = survey.Revision
+	return model, nil
+}
 
-	// 从 QuestionIDs 中逐个查询 Question 表
-	questions := make([]QuestionModel, 0)
-	for _, questionID := range questionIDArray {
-		var question common.Question
-		err := common.DB.Where("QuestionID = ?", questionID).First(&question).Error
-		if err != nil {
-			return nil, errors.New("Failed to find question: " + questionID)
+// loadEditingModel 读取问卷的草稿，没有草稿时读取已发布的题目
+func loadEditingModel(survey *common.Survey) (*SurveyModel, error) {
+	if survey.DraftContent != "" {
+		var model SurveyModel
+		if err := json.Unmarshal([]byte(survey.DraftContent), &model); err != nil {
+			return nil, errors.New("failed to decode survey draft")
 		}
+		return &model, nil
+	}
+	return loadPublishedModel(common.DB, survey)
+}
 
-		// 查询选项
-		var options []common.QuestionOption
-		if question.OptionIDs != "" {
-			optionIDs := strings.Split(question.OptionIDs, ",")
-			for _, optionID := range optionIDs {
-				var option common.QuestionOption
-				err := common.DB.Where("OptionID = ? AND SurveyID = ?", optionID, surveyId).First(&option).Error
-				if err != nil {
-					return nil, errors.New("Option not found for optionID: " + optionID)
-				}
-				options = append(options, option)
-			}
-		}
+// loadPublishedModel 按 QuestionIDs 顺序读取已发布的题目
+func loadPublishedModel(tx *gorm.DB, survey *common.Survey) (*SurveyModel, error) {
+	questions, err := loadQuestionModels(tx, survey.SurveyID, survey.QuestionIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &SurveyModel{
+		ID:                 survey.SurveyID,
+		Title:              survey.Title,
+		Questions:          questions,
+		RandomizeQuestions: survey.RandomizeQuestions,
+		QuizMode:           survey.QuizMode,
+		ShowScore:          survey.ShowScore,
+		Variables:          decodeVariables(survey.Variables),
+	}, nil
+}
 
-		// 查询数字填空
-		var numFillIns []common.QuestionNumFillIn
-		if question.NumFillInIDs != "" {
-			numFillInIDs := strings.Split(question.NumFillInIDs, ",")
-			for _, numFillInID := range numFillInIDs {
-				var numFillIn common.QuestionNumFillIn
-				err := common.DB.Where("NumFillInID = ? AND SurveyID = ?", numFillInID, surveyId).First(&numFillIn).Error
-				if err != nil {
-					return nil, errors.New("NumFillIn not found for NumFillInID: " + numFillInID)
-				}
-				numFillIns = append(numFillIns, numFillIn)
-			}
+// normalizeSurveyModel 补全题目、选项和填空的所属问卷与题目 ID
+func normalizeSurveyModel(surveyId string, model *SurveyModel) {
+	model.ID = surveyId
+	for i := range model.Questions {
+		question := &model.Questions[i]
+		question.SurveyID = surveyId
+		for j := range question.Options {
+			question.Options[j].QuestionID = question.QuestionID
+			question.Options[j].SurveyID = surveyId
 		}
-
-		// 查询文本填空
-		var textFillIns []common.QuestionTextFillIn
-		if question.TextFillInIDs != "" {
-			textFillInIDs := strings.Split(question.TextFillInIDs, ",")
-			for _, textFillInID := range textFillInIDs {
-				var textFillIn common.QuestionTextFillIn
-				err := common.DB.Where("TextFillInID = ? AND SurveyID = ?", textFillInID, surveyId).First(&textFillIn).Error
-				if err != nil {
-					return nil, errors.New("TextFillIn not found for TextFillInID: " + textFillInID)
-				}
-				textFillIns = append(textFillIns, textFillIn)
-			}
+		for j := range question.TextFillIns {
+			question.TextFillIns[j].QuestionID = question.QuestionID
+			question.TextFillIns[j].SurveyID = surveyId
+		}
+		for j := range question.NumFillIns {
+			question.NumFillIns[j].QuestionID = question.QuestionID
+			question.NumFillIns[j].SurveyID = surveyId
 		}
-
-		// 将 Question 转换为 QuestionModel
-		questions = append(questions, QuestionModel{
-			Type:        question.QuestionType,
-			Label:       question.QuestionLabel,
-			QuestionID:  question.QuestionID,
-			Title:       question.Title,
-			Description: question.Description,
-			LeastChoice: question.LeastChoice,
-			MaxChoice:   question.MaxChoice,
-			SurveyID:    question.SurveyID,
-			Options:     options,     // 直接使用查询结果，无需再构建
-			NumFillIns:  numFillIns,  // 直接使用查询结果，无需再构建
-			TextFillIns: textFillIns, // 直接使用查询结果，无需再构建
-		})
 	}
-
-	// 构造响应数据
-	return &SurveyModel{
-		ID:        survey.SurveyID,
-		Title:     survey.Title,
-		IsOpening: survey.Status == "open",
-		Questions: questions,
-	}, nil
 }
 
-// SaveSurveyEditService 处理问卷编辑保存的服务层逻辑
-func SaveSurveyEditService(surveyId string, surveyData *SurveyModel) error {
+// SaveSurveyEditService 将编辑内容保存为草稿，每次保存生成一个新版本。
+// expectedRevision 为编辑者读取时的修订号，问卷已被他人修改时返回 ErrRevisionConflict
+func SaveSurveyEditService(surveyId, userID string, expectedRevision int, surveyData *SurveyModel) (int, error) {
 	// 检查问卷是否存在
-	var survey common.Survey
-	err := common.DB.Where("SurveyID = ?", surveyId).First(&survey).Error
+	survey, err := getOwnedSurvey(surveyId, userID)
 	if err != nil {
-		return errors.New("survey not found")
+		return 0, err
 	}
 
-	// 更新问卷信息
-	survey.Title = surveyData.Title
-	survey.Status = "Ongoing"
-
-	err = common.DB.Save(&survey).Error
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := saveSurveyContent(tx, survey, surveyData, userID, "", expectedRevision)
+		return err
+	})
 	if err != nil {
-		return errors.New("failed to update survey")
+		return 0, err
 	}
+	return survey.Revision, nil
+}
 
-	// 删除旧问题及其相关数据
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.Question{}).Error
-	if err != nil {
-		return errors.New("failed to delete old questions")
+// saveSurveyContent 在事务中保存问卷草稿并记录版本快照，返回新版本号。
+// expectedRevision 小于 0 时不做修订号校验
+func saveSurveyContent(tx *gorm.DB, survey *common.Survey, surveyData *SurveyModel, userID, note string, expectedRevision int) (int, error) {
+	// 校验并递增修订号，同时锁定问卷记录
+	if err := touchSurvey(tx, survey, userID, expectedRevision); err != nil {
+		return 0, err
+	}
+
+	// 校验题目规则设置
+	if errs := validateQuestionRules("questions", surveyData.Questions); len(errs) > 0 {
+		return 0, errors.New("invalid question rules: " + strings.Join(errs, "; "))
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionOption{}).Error
+	if errs := validateSurveyLogic(surveyData.Questions, surveyData.Variables); len(errs) > 0 {
+		return 0, errors.New("invalid survey logic: " + strings.Join(errs, "; "))
+	}
+
+	normalizeSurveyModel(survey.SurveyID, surveyData)
+	surveyData.IsOpening = false
+	surveyData.Seed = ""
+	surveyData.Language, surveyData.Languages, surveyData.Texts = "", nil, nil
+	surveyData.Appearance = nil
+	draft, err := json.Marshal(surveyData)
 	if err != nil {
-		return errors.New("failed to delete old options")
+		return 0, errors.New("failed to encode survey draft")
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionTextFillIn{}).Error
+
+	// 更新草稿和版本号，已发布的题目在发布前保持不变
+	survey.CurrentVersion++
+	err = tx.Model(survey).Updates(map[string]interface{}{
+		"DraftContent":   string(draft),
+		"CurrentVersion": survey.CurrentVersion,
+	}).Error
 	if err != nil {
-		return errors.New("failed to delete old text fill-ins")
+		return 0, errors.New("failed to update survey")
+	}
+	survey.DraftContent = string(draft)
+
+	// 记录版本快照
+	if err := createSurveyVersion(tx, survey, string(draft), userID, note); err != nil {
+		return 0, err
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionNumFillIn{}).Error
+	return survey.CurrentVersion, nil
+}
+
+// PublishSurveyDraftService 将草稿原子地发布为答题者可见的题目，返回新的修订号。
+// expectedRevision 与当前修订号不一致时返回 ErrRevisionConflict
+func PublishSurveyDraftService(surveyId, userID string, expectedRevision int) (int, error) {
+	survey, err := getOwnedSurvey(surveyId, userID)
 	if err != nil {
-		return errors.New("failed to delete old num fill-ins")
+		return 0, err
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionResponse{}).Error
+
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := touchSurvey(tx, survey, userID, expectedRevision); err != nil {
+			return err
+		}
+
+		// touchSurvey 已锁住该行，在事务内重新读取草稿，避免发布读取之后被覆盖的旧草稿
+		var current common.Survey
+		if err := tx.Select("DraftContent", "CurrentVersion").Where("SurveyID = ?", surveyId).First(&current).Error; err != nil {
+			return errors.New("survey not found")
+		}
+		if current.DraftContent == "" {
+			return errors.New("no draft to publish")
+		}
+		var draft SurveyModel
+		if err := json.Unmarshal([]byte(current.DraftContent), &draft); err != nil {
+			return errors.New("failed to decode survey draft")
+		}
+		survey.CurrentVersion = current.CurrentVersion
+
+		// 删除旧问题及其相关数据，已提交的答卷保留并通过版本号区分
+		if err := deleteQuestions(tx, surveyId); err != nil {
+			return err
+		}
+
+		// 保存新问题及其相关数据
+		questionIDs, err := createQuestions(tx, surveyId, draft.Questions)
+		if err != nil {
+			return err
+		}
+
+		// 更新问卷信息，将问题 ID 列表保存为以逗号分隔的字符串，并清空草稿
+		err = tx.Model(survey).Updates(map[string]interface{}{
+			"Title":              draft.Title,
+			"QuestionIDsList":    questionIDs,
+			"PublishedVersion":   survey.CurrentVersion,
+			"DraftContent":       "",
+			"RandomizeQuestions": draft.RandomizeQuestions,
+			"QuizMode":           draft.QuizMode,
+			"ShowScore":          draft.ShowScore,
+			"Variables":          encodeVariables(draft.Variables),
+		}).Error
+		if err != nil {
+			return errors.New("failed to publish survey")
+		}
+
+		// 草稿状态的问卷在首次发布题目时进入发布状态
+		if NormalizeStatus(survey.Status) == StatusDraft {
+			if err := tx.Model(survey).Update("Status", StatusPublished).Error; err != nil {
+				return errors.New("failed to update status")
+			}
+			return recordStatusChange(tx, surveyId, StatusDraft, StatusPublished, userID)
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.New("failed to delete old responsequestion fill-ins")
+		return 0, err
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.ResponseOption{}).Error
+	return survey.Revision, nil
+}
+
+// DiscardSurveyDraftService 丢弃草稿，恢复为已发布的题目
+func DiscardSurveyDraftService(surveyId, userID string) error {
+	survey, err := getOwnedSurvey(surveyId, userID)
 	if err != nil {
-		return errors.New("failed to delete old responseoption fill-ins")
+		return err
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.ResponseTextFillIn{}).Error
+	if survey.DraftContent == "" {
+		return nil
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := touchSurvey(tx, survey, userID, -1); err != nil {
+			return err
+		}
+		if err := tx.Model(survey).Update("DraftContent", "").Error; err != nil {
+			return errors.New("failed to discard draft")
+		}
+		return nil
+	})
+}
+
+// DeleteSurveyService 处理问卷删除逻辑，问卷将被移入回收站
+func DeleteSurveyService(surveyId, userID string) error {
+	return TrashSurvey(surveyId, userID)
+}
+
+// deleteQuestions 删除问卷下的所有问题、选项和填空
+func deleteQuestions(tx *gorm.DB, surveyId string) error {
+	err := tx.Where("SurveyID = ?", surveyId).Delete(&common.Question{}).Error
 	if err != nil {
-		return errors.New("failed to delete old responsetext fill-ins")
+		return errors.New("failed to delete old questions")
 	}
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.ResponseNumFillIn{}).Error
+	err = tx.Where("SurveyID = ?", surveyId).Delete(&common.QuestionOption{}).Error
 	if err != nil {
-		return errors.New("failed to delete old responsenumfill fill-ins")
+		return errors.New("failed to delete old options")
 	}
-	err = common.DB.Debug().Where("SurveyID = ?", surveyId).Delete(&common.SurveyResponse{}).Error
+	err = tx.Where("SurveyID = ?", surveyId).Delete(&common.QuestionTextFillIn{}).Error
 	if err != nil {
-		return errors.New("failed to delete old response fill-ins")
+		return errors.New("failed to delete old text fill-ins")
 	}
-	//清空 Survey 中的 ResponseCount 字段
-	err = common.DB.Debug().Model(&survey).Where("SurveyID = ?", surveyId).Update("ResponseCount", 0).Error
+	err = tx.Where("SurveyID = ?", surveyId).Delete(&common.QuestionNumFillIn{}).Error
 	if err != nil {
-		return errors.New("failed to reset response count")
+		return errors.New("failed to delete old num fill-ins")
 	}
+	return nil
+}
 
-	// if err := common.DB.Model(&survey).Where("SurveyID = ?", surveyId).Update("ResponseCount", survey.ResponseCount+1).Error; err != nil {
-	// 	return errors.New("failed to update survey response count: " + err.Error())
-	// }
-
+// createQuestions 按顺序保存问题及其选项和填空，返回以逗号分隔的问题 ID 列表
+func createQuestions(tx *gorm.DB, surveyId string, questions []QuestionModel) (string, error) {
 	// 存储问题 ID 的列表
 	questionIDs := []string{}
 
 	// 保存新问题及其相关数据
-	for _, question := range surveyData.Questions {
+	for _, question := range questions {
 		// 收集选项 IDs
 		optionIDs := []string{}
 		for _, option := range question.Options {
@@ -316,138 +430,151 @@ func SaveSurveyEditService(surveyId string, surveyData *SurveyModel) error {
 
 		// 构造问题数据
 		newQuestion := common.Question{
-			QuestionID:    question.QuestionID,
-			SurveyID:      surveyId,
-			Title:         question.Title,
-			Description:   question.Description,
-			LeastChoice:   int(question.LeastChoice),
-			MaxChoice:     int(question.MaxChoice),
-			QuestionType:  question.Type,
-			QuestionLabel: question.Label,
-			OptionIDs:     strings.Join(optionIDs, ","),
-			TextFillInIDs: strings.Join(textFillInIDs, ","),
-			NumFillInIDs:  strings.Join(numFillInIDs, ","),
+			QuestionID:       question.QuestionID,
+			SurveyID:         surveyId,
+			Title:            question.Title,
+			Description:      question.Description,
+			LeastChoice:      int(question.LeastChoice),
+			MaxChoice:        int(question.MaxChoice),
+			QuestionType:     question.Type,
+			QuestionLabel:    question.Label,
+			OptionIDs:        strings.Join(optionIDs, ","),
+			TextFillInIDs:    strings.Join(textFillInIDs, ","),
+			NumFillInIDs:     strings.Join(numFillInIDs, ","),
+			Required:         question.Required,
+			RequiredMessage:  question.RequiredMessage,
+			BankQuestionID:   question.BankQuestionID,
+			RandomizeOptions: question.RandomizeOptions,
+			PageBreak:        question.PageBreak,
+			Points:           question.Points,
+			PartialCredit:    question.PartialCredit,
+			DisplayCondition: question.DisplayCondition,
+			Image:            question.Image,
 		}
 
 		// 插入新问题
-		err = common.DB.Create(&newQuestion).Error
+		err := tx.Create(&newQuestion).Error
 		if err != nil {
-			return errors.New("Failed to save question: " + question.QuestionID)
+			return "", errors.New("Failed to save question: " + question.QuestionID)
 		}
 
 		// 保存问题选项
 		for _, option := range question.Options {
-			newOption := common.QuestionOption{
-				OptionID:      option.OptionID,
-				QuestionID:    question.QuestionID,
-				SurveyID:      surveyId,
-				OptionContent: option.OptionContent,
-			}
-			err = common.DB.Create(&newOption).Error
+			newOption := option
+			newOption.QuestionID = question.QuestionID
+			newOption.SurveyID = surveyId
+			err = tx.Create(&newOption).Error
 			if err != nil {
-				return errors.New("Failed to save option: " + option.OptionID)
+				return "", errors.New("Failed to save option: " + option.OptionID)
 			}
 		}
 
 		// 保存文本填空
 		for _, textFillIn := range question.TextFillIns {
-			newTextFillIn := common.QuestionTextFillIn{
-				TextFillInID: textFillIn.TextFillInID,
-				QuestionID:   question.QuestionID,
-				SurveyID:     surveyId,
-			}
-			err = common.DB.Create(&newTextFillIn).Error
+			newTextFillIn := textFillIn
+			newTextFillIn.QuestionID = question.QuestionID
+			newTextFillIn.SurveyID = surveyId
+			err = tx.Create(&newTextFillIn).Error
 			if err != nil {
-				return errors.New("Failed to save text fill-in: " + textFillIn.TextFillInID)
+				return "", errors.New("Failed to save text fill-in: " + textFillIn.TextFillInID)
 			}
 		}
 
 		// 保存数字填空
 		for _, numFillIn := range question.NumFillIns {
-			newNumFillIn := common.QuestionNumFillIn{
-				NumFillInID: numFillIn.NumFillInID,
-				QuestionID:  question.QuestionID,
-				SurveyID:    surveyId,
-			}
-			err = common.DB.Create(&newNumFillIn).Error
+			newNumFillIn := numFillIn
+			newNumFillIn.QuestionID = question.QuestionID
+			newNumFillIn.SurveyID = surveyId
+			err = tx.Create(&newNumFillIn).Error
 			if err != nil {
-				return errors.New("Failed to save num fill-in: " + numFillIn.NumFillInID)
+				return "", errors.New("Failed to save num fill-in: " + numFillIn.NumFillInID)
 			}
 		}
 	}
 
-	// 将问题 ID 列表保存为以逗号分隔的字符串
-	survey.QuestionIDs = strings.Join(questionIDs, ",")
-	err = common.DB.Save(&survey).Error
-	if err != nil {
-		return errors.New("failed to save survey question IDs")
-	}
-
-	return nil
+	return strings.Join(questionIDs, ","), nil
 }
 
-// DeleteSurveyService 处理问卷删除逻辑
-func DeleteSurveyService(surveyId string) error {
-	// 检查问卷是否存在
-	var survey common.Survey
-	err := common.DB.Where("SurveyID = ?", surveyId).First(&survey).Error
-	if err != nil {
-		return errors.New("survey not found")
-	}
-
-	// 删除问卷相关联的问题
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.Question{}).Error
-	if err != nil {
-		return errors.New("failed to delete questions related to the survey")
-	}
-
-	// 删除问卷相关联的问题选项
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionOption{}).Error
-	if err != nil {
-		return errors.New("failed to delete question options related to the survey")
-	}
-
-	// 删除问卷相关联的文本填空
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionTextFillIn{}).Error
-	if err != nil {
-		return errors.New("failed to delete text fill-ins related to the survey")
-	}
-
-	// 删除问卷相关联的数字填空
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.QuestionNumFillIn{}).Error
-	if err != nil {
-		return errors.New("failed to delete num fill-ins related to the survey")
-	}
+// loadQuestionModels 按 QuestionIDs 顺序加载题目及其选项和填空
+func loadQuestionModels(tx *gorm.DB, surveyId string, questionIDs string) ([]QuestionModel, error) {
+	// 将 QuestionIDs 转换为问题 ID 的数组
+	questionIDArray := splitIDs(questionIDs)
 
-	// 删除问卷本身
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.Survey{}).Error
-	if err != nil {
-		return errors.New("failed to delete survey")
-	}
+	// 从 QuestionIDs 中逐个查询 Question 表
+	questions := make([]QuestionModel, 0)
+	for _, questionID := range questionIDArray {
+		var question common.Question
+		err := tx.Where("QuestionID = ?", questionID).First(&question).Error
+		if err != nil {
+			return nil, errors.New("Failed to find question: " + questionID)
+		}
 
-	// 删除问卷相关联的答卷选项
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.ResponseOption{}).Error
-	if err != nil {
-		return errors.New("failed to delete response options related to the survey")
-	}
+		// 查询选项
+		var options []common.QuestionOption
+		if question.OptionIDs != "" {
+			optionIDs := splitIDs(question.OptionIDs)
+			for _, optionID := range optionIDs {
+				var option common.QuestionOption
+				err := tx.Where("OptionID = ? AND SurveyID = ?", optionID, surveyId).First(&option).Error
+				if err != nil {
+					return nil, errors.New("Option not found for optionID: " + optionID)
+				}
+				options = append(options, option)
+			}
+		}
 
-	// 删除问卷相关联的文本填空答卷
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.ResponseTextFillIn{}).Error
-	if err != nil {
-		return errors.New("failed to delete response text fill-ins related to the survey")
-	}
+		// 查询数字填空
+		var numFillIns []common.QuestionNumFillIn
+		if question.NumFillInIDs != "" {
+			numFillInIDs := splitIDs(question.NumFillInIDs)
+			for _, numFillInID := range numFillInIDs {
+				var numFillIn common.QuestionNumFillIn
+				err := tx.Where("NumFillInID = ? AND SurveyID = ?", numFillInID, surveyId).First(&numFillIn).Error
+				if err != nil {
+					return nil, errors.New("NumFillIn not found for NumFillInID: " + numFillInID)
+				}
+				numFillIns = append(numFillIns, numFillIn)
+			}
+		}
 
-	// 删除问卷相关联的答卷
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.SurveyResponse{}).Error
-	if err != nil {
-		return errors.New("failed to delete survey responses related to the survey")
-	}
+		// 查询文本填空
+		var textFillIns []common.QuestionTextFillIn
+		if question.TextFillInIDs != "" {
+			textFillInIDs := splitIDs(question.TextFillInIDs)
+			for _, textFillInID := range textFillInIDs {
+				var textFillIn common.QuestionTextFillIn
+				err := tx.Where("TextFillInID = ? AND SurveyID = ?", textFillInID, surveyId).First(&textFillIn).Error
+				if err != nil {
+					return nil, errors.New("TextFillIn not found for TextFillInID: " + textFillInID)
+				}
+				textFillIns = append(textFillIns, textFillIn)
+			}
+		}
 
-	// 删除问卷相关联的数字填空答卷
-	err = common.DB.Where("SurveyID = ?", surveyId).Delete(&common.ResponseNumFillIn{}).Error
-	if err != nil {
-		return errors.New("failed to delete response num fill-ins related to the survey")
+		// 将 Question 转换为 QuestionModel
+		questions = append(questions, QuestionModel{
+			Type:             question.QuestionType,
+			Label:            question.QuestionLabel,
+			QuestionID:       question.QuestionID,
+			Title:            question.Title,
+			Description:      question.Description,
+			LeastChoice:      question.LeastChoice,
+			MaxChoice:        question.MaxChoice,
+			SurveyID:         question.SurveyID,
+			Options:          options,     // 直接使用查询结果，无需再构建
+			NumFillIns:       numFillIns,  // 直接使用查询结果，无需再构建
+			TextFillIns:      textFillIns, // 直接使用查询结果，无需再构建
+			Required:         question.Required,
+			RequiredMessage:  question.RequiredMessage,
+			BankQuestionID:   question.BankQuestionID,
+			RandomizeOptions: question.RandomizeOptions,
+			PageBreak:        question.PageBreak,
+			Points:           question.Points,
+			PartialCredit:    question.PartialCredit,
+			DisplayCondition: question.DisplayCondition,
+			Image:            question.Image,
+		})
 	}
 
-	return nil
+	return questions, nil
 }
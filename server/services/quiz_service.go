@@ -0,0 +1,262 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"server/common"
+	"sort"
+	"strings"
+)
+
+// SubmitResult 提交答卷的结果，测验模式且允许显示得分时包含得分
+type SubmitResult struct {
+	Score    *float64 `json:"score,omitempty"`
+	MaxScore float64  `json:"maxScore,omitempty"`
+}
+
+// ScoreBucket 得分分布区间，按得分率划分
+type ScoreBucket struct {
+	From  float64 `json:"from"` // 得分率下限（含）
+	To    float64 `json:"to"`   // 得分率上限（不含，最后一个区间包含 1）
+	Count int     `json:"count"`
+}
+
+// QuestionDifficulty 单题难度统计
+type QuestionDifficulty struct {
+	QuestionID   string  `json:"questionId"`
+	Title        string  `json:"title"`
+	Points       float64 `json:"points"`
+	AverageScore float64 `json:"averageScore"`
+	Difficulty   float64 `json:"difficulty"`   // 平均得分率，越低越难
+	CorrectCount int     `json:"correctCount"` // 得满分的答卷数
+}
+
+// QuizStats 测验成绩统计
+type QuizStats struct {
+	ResponseCount int                  `json:"responseCount"`
+	MaxScore      float64              `json:"maxScore"`
+	AverageScore  float64              `json:"averageScore"`
+	MedianScore   float64              `json:"medianScore"`
+	HighestScore  float64              `json:"highestScore"`
+	LowestScore   float64              `json:"lowestScore"`
+	Distribution  []ScoreBucket        `json:"distribution"`
+	Questions     []QuestionDifficulty `json:"questions"`
+}
+
+// stripAnswerKey 去掉返回给答题者的正确答案
+func stripAnswerKey(model *SurveyModel) {
+	for i := range model.Questions {
+		question := &model.Questions[i]
+		for j := range question.Options {
+			question.Options[j].IsCorrect = false
+		}
+		for j := range question.TextFillIns {
+			question.TextFillIns[j].AcceptedAnswers = nil
+		}
+	}
+}
+
+// normalizeAnswer 比较文本答案时忽略大小写和首尾空白
+func normalizeAnswer(text string) string {
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// gradeQuestion 计算单题得分，未设置分值或答案的题目不计分
+func gradeQuestion(question QuestionModel, answer *QuestionResponseModel) (float64, bool) {
+	if question.Points <= 0 {
+		return 0, false
+	}
+
+	switch question.Type {
+	case "SingleChoice", "MultiChoice":
+		correct := map[string]bool{}
+		for _, option := range question.Options {
+			if option.IsCorrect {
+				correct[option.OptionID] = true
+			}
+		}
+		if len(correct) == 0 {
+			return 0, false
+		}
+		if answer == nil {
+			return 0, true
+		}
+
+		hits, misses := 0, 0
+		for _, option := range answer.Options {
+			if !option.IsSelect {
+				continue
+			}
+			if correct[option.OptionID] {
+				hits++
+			} else {
+				misses++
+			}
+		}
+		// 部分给分时每选错一项抵消一项正确选择
+		if question.PartialCredit && question.Type == "MultiChoice" {
+			ratio := math.Max(0, float64(hits-misses)/float64(len(correct)))
+			return question.Points * ratio, true
+		}
+		if hits == len(correct) && misses == 0 {
+			return question.Points, true
+		}
+		return 0, true
+
+	case "SingleTextFillIn", "MultiTextFillIn":
+		accepted := map[string]map[string]bool{}
+		for _, textFillIn := range question.TextFillIns {
+			if len(textFillIn.AcceptedAnswers) == 0 {
+				continue
+			}
+			answers := map[string]bool{}
+			for _, text := range textFillIn.AcceptedAnswers {
+				answers[normalizeAnswer(text)] = true
+			}
+			accepted[textFillIn.TextFillInID] = answers
+		}
+		if len(accepted) == 0 {
+			return 0, false
+		}
+		if answer == nil {
+			return 0, true
+		}
+
+		hits := 0
+		for _, textFillIn := range answer.TextFillIns {
+			if answers, ok := accepted[textFillIn.TextFillInID]; ok && answers[normalizeAnswer(textFillIn.TextContent)] {
+				hits++
+			}
+		}
+		if question.PartialCredit {
+			return question.Points * float64(hits) / float64(len(accepted)), true
+		}
+		if hits == len(accepted) {
+			return question.Points, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// gradeResponse 按正确答案为答卷评分，返回总分、满分和各题得分
+func gradeResponse(questions []QuestionModel, response *ResponseModel) (float64, float64, map[string]float64) {
+	answers := map[string]*QuestionResponseModel{}
+	for i := range response.QuestionsResponse {
+		answers[response.QuestionsResponse[i].QID] = &response.QuestionsResponse[i]
+	}
+
+	score, maxScore := 0.0, 0.0
+	scores := map[string]float64{}
+	for _, question := range questions {
+		points, graded := gradeQuestion(question, answers[question.QuestionID])
+		if !graded {
+			continue
+		}
+		// 保留两位小数，避免部分给分产生的浮点误差
+		points = math.Round(points*100) / 100
+		scores[question.QuestionID] = points
+		score += points
+		maxScore += question.Points
+	}
+	return score, maxScore, scores
+}
+
+// GetQuizStats 获取测验的得分分布和各题难度，只统计有效答卷
+func GetQuizStats(surveyID, userID string) (*QuizStats, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !survey.QuizMode {
+		return nil, errors.New("survey is not in quiz mode")
+	}
+	questions, err := loadQuestionModels(common.DB, survey.SurveyID, survey.QuestionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []common.SurveyResponse
+	if err := common.DB.Where("SurveyID = ? AND Score IS NOT NULL AND IsInvalid = ?", surveyID, false).Find(&responses).Error; err != nil {
+		return nil, errors.New("failed to retrieve responses")
+	}
+
+	stats := &QuizStats{
+		ResponseCount: len(responses),
+		Distribution:  []ScoreBucket{},
+		Questions:     []QuestionDifficulty{},
+	}
+	for i := 0; i < 10; i++ {
+		stats.Distribution = append(stats.Distribution, ScoreBucket{From: float64(i) / 10, To: float64(i+1) / 10})
+	}
+
+	points := map[string]float64{}
+	for _, question := range questions {
+		points[question.QuestionID] = question.Points
+	}
+
+	totals := map[string]float64{}
+	correct := map[string]int{}
+	answered := map[string]int{}
+	scores := []float64{}
+	for _, response := range responses {
+		score := *response.Score
+		scores = append(scores, score)
+		if response.MaxScore > 0 {
+			bucket := int(score / response.MaxScore * 10)
+			if bucket >= 10 {
+				bucket = 9
+			}
+			if bucket < 0 {
+				bucket = 0
+			}
+			stats.Distribution[bucket].Count++
+		}
+
+		questionScores := map[string]float64{}
+		json.Unmarshal([]byte(response.QuestionScores), &questionScores)
+		for questionID, earned := range questionScores {
+			totals[questionID] += earned
+			answered[questionID]++
+			if earned >= points[questionID] {
+				correct[questionID]++
+			}
+		}
+	}
+
+	if len(scores) > 0 {
+		sort.Float64s(scores)
+		sum := 0.0
+		for _, score := range scores {
+			sum += score
+		}
+		stats.AverageScore = sum / float64(len(scores))
+		stats.LowestScore = scores[0]
+		stats.HighestScore = scores[len(scores)-1]
+		if len(scores)%2 == 1 {
+			stats.MedianScore = scores[len(scores)/2]
+		} else {
+			stats.MedianScore = (scores[len(scores)/2-1] + scores[len(scores)/2]) / 2
+		}
+	}
+
+	for _, question := range questions {
+		if _, graded := gradeQuestion(question, nil); !graded {
+			continue
+		}
+		stats.MaxScore += question.Points
+		difficulty := QuestionDifficulty{
+			QuestionID:   question.QuestionID,
+			Title:        question.Title,
+			Points:       question.Points,
+			CorrectCount: correct[question.QuestionID],
+		}
+		if count := answered[question.QuestionID]; count > 0 {
+			difficulty.AverageScore = totals[question.QuestionID] / float64(count)
+			difficulty.Difficulty = difficulty.AverageScore / question.Points
+		}
+		stats.Questions = append(stats.Questions, difficulty)
+	}
+	return stats, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"server/common"
+	"testing"
+)
+
+// choiceAnswer 构造选中指定选项的作答
+func choiceAnswer(selected ...string) *QuestionResponseModel {
+	answer := &QuestionResponseModel{}
+	for _, optionID := range []string{"a", "b", "c", "d"} {
+		isSelect := false
+		for _, id := range selected {
+			isSelect = isSelect || id == optionID
+		}
+		answer.Options = append(answer.Options, common.ResponseOption{OptionID: optionID, IsSelect: isSelect})
+	}
+	return answer
+}
+
+// textAnswer 构造依次填写各填空的作答
+func textAnswer(texts ...string) *QuestionResponseModel {
+	answer := &QuestionResponseModel{}
+	for i, text := range texts {
+		answer.TextFillIns = append(answer.TextFillIns, common.ResponseTextFillIn{TextFillInID: string(rune('x' + i)), TextContent: text})
+	}
+	return answer
+}
+
+func TestGradeQuestion(t *testing.T) {
+	options := func(correct ...string) []common.QuestionOption {
+		result := []common.QuestionOption{}
+		for _, optionID := range []string{"a", "b", "c", "d"} {
+			isCorrect := false
+			for _, id := range correct {
+				isCorrect = isCorrect || id == optionID
+			}
+			result = append(result, common.QuestionOption{OptionID: optionID, IsCorrect: isCorrect})
+		}
+		return result
+	}
+	single := QuestionModel{Type: "SingleChoice", Points: 5, Options: options("b")}
+	multi := QuestionModel{Type: "MultiChoice", Points: 4, Options: options("a", "b")}
+	partial := multi
+	partial.PartialCredit = true
+	texts := QuestionModel{Type: "MultiTextFillIn", Points: 6, TextFillIns: []common.QuestionTextFillIn{
+		{TextFillInID: "x", AcceptedAnswers: []string{"Paris", "巴黎"}},
+		{TextFillInID: "y", AcceptedAnswers: []string{"Berlin"}},
+	}}
+	partialTexts := texts
+	partialTexts.PartialCredit = true
+
+	tests := []struct {
+		name     string
+		question QuestionModel
+		answer   *QuestionResponseModel
+		want     float64
+		graded   bool
+	}{
+		{"no points", QuestionModel{Type: "SingleChoice", Options: options("a")}, choiceAnswer("a"), 0, false},
+		{"no correct option", QuestionModel{Type: "SingleChoice", Points: 5, Options: options()}, choiceAnswer("a"), 0, false},
+		{"ungraded type", QuestionModel{Type: "SingleNumFillIn", Points: 5}, &QuestionResponseModel{}, 0, false},
+		{"single correct", single, choiceAnswer("b"), 5, true},
+		{"single wrong", single, choiceAnswer("a"), 0, true},
+		{"single unanswered", single, nil, 0, true},
+		{"multi all correct", multi, choiceAnswer("a", "b"), 4, true},
+		{"multi missing one", multi, choiceAnswer("a"), 0, true},
+		{"multi extra wrong", multi, choiceAnswer("a", "b", "c"), 0, true},
+		{"partial missing one", partial, choiceAnswer("a"), 2, true},
+		{"partial extra wrong", partial, choiceAnswer("a", "b", "c"), 2, true},
+		{"partial never negative", partial, choiceAnswer("a", "c", "d"), 0, true},
+		{"text all correct", texts, textAnswer(" paris ", "BERLIN"), 6, true},
+		{"text alternative answer", texts, textAnswer("巴黎", "Berlin"), 6, true},
+		{"text one wrong", texts, textAnswer("Paris", "Rome"), 0, true},
+		{"partial text one wrong", partialTexts, textAnswer("Paris", "Rome"), 3, true},
+		{"text unanswered", texts, nil, 0, true},
+	}
+	for _, tt := range tests {
+		got, graded := gradeQuestion(tt.question, tt.answer)
+		if got != tt.want || graded != tt.graded {
+			t.Errorf("%s: gradeQuestion = (%v, %v), want (%v, %v)", tt.name, got, graded, tt.want, tt.graded)
+		}
+	}
+}
+
+func TestGradeResponse(t *testing.T) {
+	questions := []QuestionModel{
+		{QuestionID: "q1", Type: "SingleChoice", Points: 2, Options: []common.QuestionOption{{OptionID: "a", IsCorrect: true}, {OptionID: "b"}}},
+		{QuestionID: "q2", Type: "MultiChoice", Points: 1, PartialCredit: true, Options: []common.QuestionOption{
+			{OptionID: "a", IsCorrect: true}, {OptionID: "b", IsCorrect: true}, {OptionID: "c", IsCorrect: true},
+		}},
+		{QuestionID: "q3", Type: "SingleTextFillIn"},
+	}
+	response := &ResponseModel{QuestionsResponse: []QuestionResponseModel{
+		{QID: "q1", Options: []common.ResponseOption{{OptionID: "a", IsSelect: true}}},
+		{QID: "q2", Options: []common.ResponseOption{{OptionID: "a", IsSelect: true}}},
+	}}
+
+	score, maxScore, scores := gradeResponse(questions, response)
+	if score != 2.33 || maxScore != 3 {
+		t.Errorf("gradeResponse = (%v, %v), want (2.33, 3)", score, maxScore)
+	}
+	if scores["q1"] != 2 || scores["q2"] != 0.33 {
+		t.Errorf("scores = %v, want q1=2 q2=0.33", scores)
+	}
+	if _, ok := scores["q3"]; ok {
+		t.Errorf("scores = %v, ungraded q3 should be absent", scores)
+	}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/common"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 配额已满时的动作
+const (
+	QuotaActionReject   = "reject"   // 拒绝提交
+	QuotaActionEnd      = "end"      // 结束答题并显示提示
+	QuotaActionRedirect = "redirect" // 跳转到指定地址
+)
+
+// QuotaCondition 配额条件：题目中选中任一指定选项即满足
+type QuotaCondition struct {
+	QuestionID string   `json:"questionId"`
+	OptionIDs  []string `json:"optionIds"`
+}
+
+// QuotaRequest 创建或修改配额的参数
+type QuotaRequest struct {
+	Name        string           `json:"name"`
+	Conditions  []QuotaCondition `json:"conditions"`
+	Cap         int              `json:"cap"`
+	Action      string           `json:"action"`
+	Message     string           `json:"message"`
+	RedirectURL string           `json:"redirectUrl"`
+}
+
+// QuotaResponse 配额信息及填充情况
+type QuotaResponse struct {
+	QuotaID     string           `json:"quotaId"`
+	Name        string           `json:"name"`
+	Conditions  []QuotaCondition `json:"conditions"`
+	Cap         int              `json:"cap"`
+	Count       int              `json:"count"`
+	FillRate    float64          `json:"fillRate"` // 已填充比例，0 到 1
+	Full        bool             `json:"full"`
+	Action      string           `json:"action"`
+	Message     string           `json:"message"`
+	RedirectURL string           `json:"redirectUrl"`
+	CreateTime  time.Time        `json:"createTime"`
+}
+
+// QuotaFullError 答卷命中的配额已满
+type QuotaFullError struct {
+	QuotaID     string
+	Action      string
+	Message     string
+	RedirectURL string
+}
+
+func (e *QuotaFullError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "quota is full"
+}
+
+// toQuotaResponse 构造配额响应
+func toQuotaResponse(quota common.SurveyQuota) QuotaResponse {
+	conditions := []QuotaCondition{}
+	json.Unmarshal([]byte(quota.Conditions), &conditions)
+
+	fillRate := 1.0
+	if quota.Cap > 0 {
+		fillRate = float64(quota.Count) / float64(quota.Cap)
+		if fillRate > 1 {
+			fillRate = 1
+		}
+	}
+	return QuotaResponse{
+		QuotaID:     quota.QuotaID,
+		Name:        quota.Name,
+		Conditions:  conditions,
+		Cap:         quota.Cap,
+		Count:       quota.Count,
+		FillRate:    fillRate,
+		Full:        quota.Count >= quota.Cap,
+		Action:      quota.Action,
+		Message:     quota.Message,
+		RedirectURL: quota.RedirectURL,
+		CreateTime:  quota.CreateTime,
+	}
+}
+
+// validateQuotaRequest 校验配额参数，条件须引用问卷当前编辑内容中的选择题选项
+func validateQuotaRequest(survey *common.Survey, request *QuotaRequest) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return errors.New("name is required")
+	}
+	if request.Cap < 0 {
+		return errors.New("cap must not be negative")
+	}
+	switch request.Action {
+	case "":
+		request.Action = QuotaActionReject
+	case QuotaActionReject, QuotaActionEnd:
+	case QuotaActionRedirect:
+		if !strings.HasPrefix(request.RedirectURL, "http://") && !strings.HasPrefix(request.RedirectURL, "https://") {
+			return errors.New("redirectUrl must be an http(s) URL")
+		}
+	default:
+		return errors.New("invalid quota action")
+	}
+	if len(request.Conditions) == 0 {
+		return errors.New("at least one condition is required")
+	}
+
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return err
+	}
+	questions := map[string]QuestionModel{}
+	for _, question := range model.Questions {
+		questions[question.QuestionID] = question
+	}
+	for i, condition := range request.Conditions {
+		question, ok := questions[condition.QuestionID]
+		if !ok || (question.Type != "SingleChoice" && question.Type != "MultiChoice") {
+			return fmt.Errorf("conditions[%d]: choice question not found", i)
+		}
+		if len(condition.OptionIDs) == 0 {
+			return fmt.Errorf("conditions[%d]: at least one option is required", i)
+		}
+		options := map[string]bool{}
+		for _, option := range question.Options {
+			options[option.OptionID] = true
+		}
+		for _, optionID := range condition.OptionIDs {
+			if !options[optionID] {
+				return fmt.Errorf("conditions[%d]: option %s not found", i, optionID)
+			}
+		}
+	}
+	return nil
+}
+
+// ListQuotas 获取问卷的配额及填充情况
+func ListQuotas(surveyID, userID string) ([]QuotaResponse, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
+	}
+
+	var quotas []common.SurveyQuota
+	if err := common.DB.Where("SurveyID = ?", surveyID).Order("CreateTime").Find(&quotas).Error; err != nil {
+		return nil, errors.New("failed to retrieve quotas")
+	}
+	responses := []QuotaResponse{}
+	for _, quota := range quotas {
+		responses = append(responses, toQuotaResponse(quota))
+	}
+	return responses, nil
+}
+
+// CreateQuota 为问卷创建配额，计数从 0 开始
+func CreateQuota(surveyID, userID string, request QuotaRequest) (*QuotaResponse, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuotaRequest(survey, &request); err != nil {
+		return nil, err
+	}
+
+	conditions, _ := json.Marshal(request.Conditions)
+	quota := common.SurveyQuota{
+		QuotaID:     uuid.New().String(),
+		SurveyID:    surveyID,
+		Name:        request.Name,
+		Conditions:  string(conditions),
+		Cap:         request.Cap,
+		Action:      request.Action,
+		Message:     request.Message,
+		RedirectURL: request.RedirectURL,
+		CreateTime:  time.Now(),
+	}
+	if err := common.DB.Create(&quota).Error; err != nil {
+		return nil, errors.New("failed to create quota")
+	}
+	response := toQuotaResponse(quota)
+	return &response, nil
+}
+
+// getOwnedQuota 获取用户问卷下的配额
+func getOwnedQuota(quotaID, userID string) (*common.SurveyQuota, *common.Survey, error) {
+	var quota common.SurveyQuota
+	if err := common.DB.Where("QuotaID = ?", quotaID).First(&quota).Error; err != nil {
+		return nil, nil, errors.New("quota not found")
+	}
+	survey, err := getOwnedSurvey(quota.SurveyID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &quota, survey, nil
+}
+
+// UpdateQuota 修改配额定义，已计入的数量保持不变
+func UpdateQuota(quotaID, userID string, request QuotaRequest) (*QuotaResponse, error) {
+	quota, survey, err := getOwnedQuota(quotaID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQuotaRequest(survey, &request); err != nil {
+		return nil, err
+	}
+
+	conditions, _ := json.Marshal(request.Conditions)
+	quota.Name = request.Name
+	quota.Conditions = string(conditions)
+	quota.Cap = request.Cap
+	quota.Action = request.Action
+	quota.Message = request.Message
+	quota.RedirectURL = request.RedirectURL
+	err = common.DB.Model(quota).Updates(map[string]interface{}{
+		"Name":        quota.Name,
+		"Conditions":  quota.Conditions,
+		"Cap":         quota.Cap,
+		"Action":      quota.Action,
+		"Message":     quota.Message,
+		"RedirectURL": quota.RedirectURL,
+	}).Error
+	if err != nil {
+		return nil, errors.New("failed to update quota")
+	}
+	response := toQuotaResponse(*quota)
+	return &response, nil
+}
+
+// DeleteQuota 删除配额
+func DeleteQuota(quotaID, userID string) error {
+	if _, _, err := getOwnedQuota(quotaID, userID); err != nil {
+		return err
+	}
+	if err := common.DB.Where("QuotaID = ?", quotaID).Delete(&common.SurveyQuota{}).Error; err != nil {
+		return errors.New("failed to delete quota")
+	}
+	return nil
+}
+
+// quotaMatches 判断答卷是否满足配额的所有条件
+func quotaMatches(conditions []QuotaCondition, selected map[string]bool) bool {
+	for _, condition := range conditions {
+		matched := false
+		for _, optionID := range condition.OptionIDs {
+			if selected[condition.QuestionID+"/"+optionID] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return len(conditions) > 0
+}
+
+// applyQuotas 在提交事务中为答卷命中的配额计数，任一配额已满时返回 QuotaFullError 以回滚事务。
+// 计数使用带上限条件的更新语句，并发提交时不会超过上限
+func applyQuotas(tx *gorm.DB, surveyID string, response *ResponseModel) error {
+	var quotas []common.SurveyQuota
+	if err := tx.Where("SurveyID = ?", surveyID).Order("CreateTime").Find(&quotas).Error; err != nil {
+		return errors.New("failed to retrieve quotas")
+	}
+	if len(quotas) == 0 {
+		return nil
+	}
+
+	selected := map[string]bool{}
+	for _, question := range response.QuestionsResponse {
+		for _, option := range question.Options {
+			if option.IsSelect {
+				selected[question.QID+"/"+option.OptionID] = true
+			}
+		}
+	}
+
+	for _, quota := range quotas {
+		var conditions []QuotaCondition
+		if err := json.Unmarshal([]byte(quota.Conditions), &conditions); err != nil {
+			continue
+		}
+		if !quotaMatches(conditions, selected) {
+			continue
+		}
+
+		result := tx.Model(&common.SurveyQuota{}).
+			Where("QuotaID = ? AND Count < Cap", quota.QuotaID).
+			Update("Count", gorm.Expr("Count + 1"))
+		if result.Error != nil {
+			return errors.New("failed to update quota")
+		}
+		if result.RowsAffected == 0 {
+			return &QuotaFullError{
+				QuotaID:     quota.QuotaID,
+				Action:      quota.Action,
+				Message:     quota.Message,
+				RedirectURL: quota.RedirectURL,
+			}
+		}
+	}
+	return nil
+}
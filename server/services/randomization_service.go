@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+)
+
+// PresentationOrder 展示给答题者的题目顺序和各题的选项顺序
+type PresentationOrder struct {
+	Questions []string            `json:"questions"`
+	Options   map[string][]string `json:"options"`
+}
+
+// seededRand 根据种子和附加键生成确定的随机数源，同一答题者每次得到相同顺序
+func seededRand(seed string, keys ...string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(seed))
+	for _, key := range keys {
+		hash.Write([]byte{0})
+		hash.Write([]byte(key))
+	}
+	return rand.New(rand.NewSource(int64(hash.Sum64())))
+}
+
+// applyPresentationOrder 按种子打乱题目和选项顺序并返回实际展示顺序，seed 为空时保持原顺序。
+// 题目只在同一页内打乱，以 PageBreak 为界；PinLast 的选项保持在末尾
+func applyPresentationOrder(model *SurveyModel, seed string) PresentationOrder {
+	if seed != "" {
+		for i := range model.Questions {
+			question := &model.Questions[i]
+			if !question.RandomizeOptions || len(question.Options) < 2 {
+				continue
+			}
+			shuffled := question.Options[:0:0]
+			pinned := question.Options[:0:0]
+			for _, option := range question.Options {
+				if option.PinLast {
+					pinned = append(pinned, option)
+				} else {
+					shuffled = append(shuffled, option)
+				}
+			}
+			random := seededRand(seed, question.QuestionID)
+			random.Shuffle(len(shuffled), func(a, b int) { shuffled[a], shuffled[b] = shuffled[b], shuffled[a] })
+			question.Options = append(shuffled, pinned...)
+		}
+
+		if model.RandomizeQuestions {
+			page := 0
+			for start := 0; start < len(model.Questions); page++ {
+				end := start + 1
+				for end < len(model.Questions) && !model.Questions[end].PageBreak {
+					end++
+				}
+
+				// 分页标记跟随页面位置，不随题目移动
+				pageBreak := model.Questions[start].PageBreak
+				pageQuestions := model.Questions[start:end]
+				random := seededRand(seed, model.ID, "page", strconv.Itoa(page))
+				random.Shuffle(len(pageQuestions), func(a, b int) {
+					pageQuestions[a], pageQuestions[b] = pageQuestions[b], pageQuestions[a]
+				})
+				for i := range pageQuestions {
+					pageQuestions[i].PageBreak = i == 0 && pageBreak
+				}
+				start = end
+			}
+		}
+	}
+
+	order := PresentationOrder{Questions: []string{}, Options: map[string][]string{}}
+	for _, question := range model.Questions {
+		order.Questions = append(order.Questions, question.QuestionID)
+		if len(question.Options) == 0 {
+			continue
+		}
+		optionIDs := []string{}
+		for _, option := range question.Options {
+			optionIDs = append(optionIDs, option.OptionID)
+		}
+		order.Options[question.QuestionID] = optionIDs
+	}
+	return order
+}
+
+// encodePresentationOrder 将展示顺序编码为 JSON 保存
+func encodePresentationOrder(order PresentationOrder) string {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
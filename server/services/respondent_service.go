@@ -1,10 +1,13 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"log"
 	"server/common"
 	"strings"
+
+	"gorm.io/gorm"
 )
 
 type QuestionModel struct {
@@ -19,6 +22,18 @@ type QuestionModel struct {
 	Options     []common.QuestionOption     `json:"Options"`
 	NumFillIns  []common.QuestionNumFillIn  `json:"NumFillIns"`
 	TextFillIns []common.QuestionTextFillIn `json:"TextFillIns"`
+
+	Required         bool    `json:"Required"`         // 是否必答
+	RequiredMessage  string  `json:"RequiredMessage"`  // 必答未填时的提示
+	BankQuestionID   string  `json:"BankQuestionID"`   // 来源题库题目ID
+	RandomizeOptions bool    `json:"RandomizeOptions"` // 是否随机打乱选项顺序
+	PageBreak        bool    `json:"PageBreak"`        // 是否在此题之前分页
+	Points           float64 `json:"Points"`           // 测验模式下本题分值
+	PartialCredit    bool    `json:"PartialCredit"`    // 是否按答对比例给分
+
+	DisplayCondition string `json:"DisplayCondition"` // 显示条件表达式，为空时总是显示
+
+	Image string `json:"Image"` // 题目图片地址
 }
 
 type SurveyModel struct {
@@ -26,12 +41,28 @@ type SurveyModel struct {
 	Title     string          `json:"title"`
 	IsOpening bool            `json:"isopening"`
 	Questions []QuestionModel `json:"questions"`
+	Revision  int             `json:"-"` // 修订号，通过 ETag 返回
+
+	RandomizeQuestions bool   `json:"randomizeQuestions"` // 是否随机打乱每页内的题目顺序
+	Seed               string `json:"seed,omitempty"`     // 答题者的随机种子，提交答卷时原样返回
+	QuizMode           bool   `json:"quizMode"`           // 是否为测验模式
+	ShowScore          bool   `json:"showScore"`          // 是否在提交后显示得分
+
+	Variables []VariableModel `json:"variables"` // 计算变量，按顺序计算，后面的变量可以引用前面的变量
+
+	Language  string       `json:"language,omitempty"`  // 返回给答题者的语言
+	Languages []string     `json:"languages,omitempty"` // 答题者可选的语言，第一个为默认语言
+	Texts     *SurveyTexts `json:"texts,omitempty"`     // 问卷级文本，仅返回给答题者
+
+	Appearance *SurveyAppearance `json:"appearance,omitempty"` // 外观设置，仅返回给答题者
 }
 
 type ResponseModel struct {
 	ResponseID        string                  `json:"ResponseID"`
 	SurveyID          string                  `json:"SurveyID"`
 	QuestionsResponse []QuestionResponseModel `json:"QuestionResponse"`
+	Seed              string                  `json:"Seed"`     // 获取问卷时返回的随机种子
+	Language          string                  `json:"Language"` // 答题语言，为空时按请求参数和 Accept-Language 确定
 }
 
 type QuestionResponseModel struct {
@@ -43,8 +74,21 @@ type QuestionResponseModel struct {
 	NumFillIns  []common.ResponseNumFillIn  `json:"NumFillIns"`
 }
 
-// GetRespondentQuestionsController 获取问卷及问题
-func GetRespondentQuestionsController(surveyId string) (*SurveyModel, error) {
+// GetRespondentQuestionsController 获取已发布的问卷及问题，草稿不会对答题者可见。
+// 题目和选项按 seed 打乱，同一答题者使用相同的 seed 时顺序不变；文本按 language 翻译
+func GetRespondentQuestionsController(surveyId, seed string, language LanguageRequest) (*SurveyModel, error) {
+	return RenderRespondentQuestionsService(surveyId, seed, language, nil)
+}
+
+// LanguageRequest 答题者请求的语言
+type LanguageRequest struct {
+	Requested      string // 请求参数或答卷中指定的语言
+	AcceptLanguage string // Accept-Language 请求头
+}
+
+// RenderRespondentQuestionsService 根据答题者已填写的答案替换题目中的占位符，
+// 并去掉按显示条件应隐藏的题目后返回问卷
+func RenderRespondentQuestionsService(surveyId, seed string, language LanguageRequest, answers []QuestionResponseModel) (*SurveyModel, error) {
 	var survey common.Survey
 
 	// 查询 Survey
@@ -53,151 +97,238 @@ func GetRespondentQuestionsController(surveyId string) (*SurveyModel, error) {
 		return nil, errors.New("survey not found")
 	}
 
-	// 将 QuestionIDs 转换为问题 ID 的数组
-	questionIDArray := strings.Split(survey.QuestionIDs, ",")
-
-	// 从 QuestionIDs 中逐个查询 Question 表
-	questions := make([]QuestionModel, 0)
-	for _, questionID := range questionIDArray {
-		var question common.Question
-		err := common.DB.Where("QuestionID = ?", questionID).First(&question).Error
-		if err != nil {
-			return nil, errors.New("Failed to find question: " + questionID)
-		}
-
-		// 查询选项
-		var options []common.QuestionOption
-		err = common.DB.Where("QuestionID = ? AND SurveyID = ?", question.QuestionID, surveyId).Find(&options).Error
-		if err != nil {
-			return nil, errors.New("Options not found for question " + question.QuestionID)
-		}
+	// 按 QuestionIDs 和 OptionIDs 的顺序读取题目、选项和填空
+	model, err := loadPublishedModel(common.DB, &survey)
+	if err != nil {
+		return nil, err
+	}
+	model.IsOpening = IsSurveyOpen(&survey)
+	model.Seed = seed
+	stripAnswerKey(model)
+	translateSurveyModel(&survey, model, ResolveLanguage(&survey, language.Requested, language.AcceptLanguage))
 
-		// 查询数字填空
-		var numFillIns []common.QuestionNumFillIn
-		err = common.DB.Where("QuestionID = ? AND SurveyID = ?", question.QuestionID, surveyId).Find(&numFillIns).Error
-		if err != nil {
-			return nil, errors.New("NumFillIns not found for question " + question.QuestionID)
-		}
+	// 外观设置中的按钮文字使用答题语言
+	appearance := appearanceOf(&survey)
+	if appearance.ButtonText != nil {
+		appearance.ButtonText = &model.Texts.ButtonText
+	}
+	model.Appearance = &appearance
+	hidden, _ := applySurveyLogic(model.Questions, model.Variables, &ResponseModel{QuestionsResponse: answers})
+	renderPipedText(model.Questions, answers, hidden)
+	applyPresentationOrder(model, seed)
 
-		// 查询文本填空
-		var textFillIns []common.QuestionTextFillIn
-		err = common.DB.Where("QuestionID = ? AND SurveyID = ?", question.QuestionID, surveyId).Find(&textFillIns).Error
-		if err != nil {
-			return nil, errors.New("TextFillIns not found for question " + question.QuestionID)
+	// 先按完整题目计算展示顺序，保证与提交时记录的顺序一致
+	if len(hidden) > 0 {
+		visible := []QuestionModel{}
+		for _, question := range model.Questions {
+			if !hidden[question.QuestionID] {
+				visible = append(visible, question)
+			}
 		}
-
-		// 将 Question 转换为 QuestionModel
-		questions = append(questions, QuestionModel{
-			Type:        question.QuestionType,
-			Label:       question.QuestionLabel,
-			QuestionID:  question.QuestionID,
-			Title:       question.Title,
-			Description: question.Description,
-			LeastChoice: question.LeastChoice,
-			MaxChoice:   question.MaxChoice,
-			SurveyID:    question.SurveyID,
-			Options:     options,     // 直接使用查询结果，无需再构建
-			NumFillIns:  numFillIns,  // 直接使用查询结果，无需再构建
-			TextFillIns: textFillIns, // 直接使用查询结果，无需再构建
-		})
-	}
-
-	// 构造响应数据
-	return &SurveyModel{
-		ID:        survey.SurveyID,
-		Title:     survey.Title,
-		IsOpening: survey.Status == "open",
-		Questions: questions,
-	}, nil
+		model.Questions = visible
+	}
+	return model, nil
 }
 
-func SubmitSurveyResponseService(response ResponseModel) error {
+// SubmitSurveyResponseService 校验并保存答卷，测验模式下自动评分
+func SubmitSurveyResponseService(response ResponseModel, acceptLanguage string) (*SubmitResult, error) {
 	// 检查问卷是否存在
 	var survey common.Survey
 	if err := common.DB.Where("SurveyID = ?", response.SurveyID).First(&survey).Error; err != nil {
-		return errors.New("survey not found")
+		return nil, errors.New("survey not found")
+	}
+
+	// 检查问卷是否处于发布状态
+	if !IsSurveyOpen(&survey) {
+		return nil, errors.New("survey is not accepting responses")
 	}
 
 	// 检查是否已存在答卷
 	var existingResponse common.SurveyResponse
 	if err := common.DB.Where("ResponseID = ?", response.ResponseID).First(&existingResponse).Error; err == nil {
-		return errors.New("response already exists")
+		return nil, errors.New("response already exists")
 	}
 
-	// 保存答卷
-	surveyResponse := common.SurveyResponse{
-		ResponseID: response.ResponseID,
-		SurveyID:   response.SurveyID,
+	// 按已发布题目的规则校验答卷
+	questions, err := loadQuestionModels(common.DB, survey.SurveyID, survey.QuestionIDs)
+	if err != nil {
+		return nil, err
 	}
-	if err := common.DB.Create(&surveyResponse).Error; err != nil {
-		return errors.New("failed to save survey response: " + err.Error())
+
+	// 按显示条件去掉隐藏题目的答案，隐藏的题目不做必答校验也不计分
+	hidden, variables := applySurveyLogic(questions, decodeVariables(survey.Variables), &response)
+	visible := []QuestionModel{}
+	for _, question := range questions {
+		if !hidden[question.QuestionID] {
+			visible = append(visible, question)
+		}
+	}
+	if errs := validateResponse(visible, &response); len(errs) > 0 {
+		return nil, &ResponseValidationError{Errors: errs}
 	}
 
-	// 保存问题答卷
-	for _, question := range response.QuestionsResponse {
-		// 初始化字段，避免 nil 数据
-		if question.Options == nil {
-			question.Options = []common.ResponseOption{}
+	// 只有选中的“其他”选项保留附带文本
+	openEnded := map[string]bool{}
+	for _, question := range questions {
+		for _, option := range question.Options {
+			if option.IsOpenEnded {
+				openEnded[option.OptionID] = true
+			}
 		}
-		if question.TextFillIns == nil {
-			question.TextFillIns = []common.ResponseTextFillIn{}
+	}
+
+	// 按答题语言和提交的答案生成答题者最终看到的题目文本
+	language := ResolveLanguage(&survey, response.Language, acceptLanguage)
+	model := &SurveyModel{
+		ID:                 survey.SurveyID,
+		Questions:          questions,
+		RandomizeQuestions: survey.RandomizeQuestions,
+	}
+	translateSurveyModel(&survey, model, language)
+	rendered := renderPipedText(model.Questions, response.QuestionsResponse, hidden)
+
+	// 根据种子重新计算展示顺序，不信任客户端上报的顺序
+	order := applyPresentationOrder(model, response.Seed)
+
+	// 测验模式下按正确答案评分
+	result := &SubmitResult{}
+	var score *float64
+	var maxScore float64
+	questionScores := ""
+	if survey.QuizMode {
+		total, max, scores := gradeResponse(visible, &response)
+		score, maxScore = &total, max
+		if data, err := json.Marshal(scores); err == nil {
+			questionScores = string(data)
 		}
-		if question.NumFillIns == nil {
-			question.NumFillIns = []common.ResponseNumFillIn{}
+		if survey.ShowScore {
+			result.Score, result.MaxScore = score, maxScore
 		}
+	}
 
-		switch question.Type {
-		case "SingleChoice", "MultiChoice": // 单选/多选题
-			for _, option := range question.Options {
-
-				responseOption := common.ResponseOption{
-					ResponseID:    response.ResponseID,
-					OptionID:      option.OptionID,
-					QuestionID:    question.QID,
-					SurveyID:      response.SurveyID,
-					OptionContent: option.OptionContent,
-					IsSelect:      option.IsSelect,
-				}
-				if err := common.DB.Create(&responseOption).Error; err != nil {
-					return errors.New("failed to save response option: " + err.Error())
-				}
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		// 配额计数，配额已满时回滚整个提交
+		if err := applyQuotas(tx, survey.SurveyID, &response); err != nil {
+			return err
+		}
+
+		// 保存答卷
+		surveyResponse := common.SurveyResponse{
+			ResponseID:        response.ResponseID,
+			SurveyID:          response.SurveyID,
+			SurveyVersion:     survey.PublishedVersion,
+			Seed:              response.Seed,
+			PresentationOrder: encodePresentationOrder(order),
+			RenderedText:      encodeRenderedText(rendered),
+			Score:             score,
+			MaxScore:          maxScore,
+			QuestionScores:    questionScores,
+			Language:          language,
+		}
+		if err := tx.Create(&surveyResponse).Error; err != nil {
+			return errors.New("failed to save survey response: " + err.Error())
+		}
+
+		// 保存计算变量
+		for name, value := range variables {
+			responseVariable := common.ResponseVariable{
+				ResponseID: response.ResponseID,
+				Name:       name,
+				SurveyID:   response.SurveyID,
+				Value:      value,
 			}
-		case "SingleTextFillIn", "MultiTextFillIn": // 单文本/多文本填空题
-			for _, textFillIn := range question.TextFillIns {
-				responseTextFillIn := common.ResponseTextFillIn{
-					ResponseID:   response.ResponseID,
-					TextFillInID: textFillIn.TextFillInID,
-					QuestionID:   question.QID,
-					SurveyID:     response.SurveyID,
-					TextContent:  textFillIn.TextContent,
-				}
-				if err := common.DB.Create(&responseTextFillIn).Error; err != nil {
-					return errors.New("failed to save text fill-in response: " + err.Error())
-				}
+			if err := tx.Create(&responseVariable).Error; err != nil {
+				return errors.New("failed to save response variable: " + err.Error())
 			}
-		case "SingleNumFillIn", "MultiNumFillIn": // 单数字/多数字填空题
-			for _, numFillIn := range question.NumFillIns {
-				responseNumFillIn := common.ResponseNumFillIn{
-					ResponseID:  response.ResponseID,
-					NumFillInID: numFillIn.NumFillInID,
-					QuestionID:  question.QID,
-					SurveyID:    response.SurveyID,
-					NumContent:  numFillIn.NumContent,
+		}
+
+		// 保存问题答卷
+		for _, question := range response.QuestionsResponse {
+			// 初始化字段，避免 nil 数据
+			if question.Options == nil {
+				question.Options = []common.ResponseOption{}
+			}
+			if question.TextFillIns == nil {
+				question.TextFillIns = []common.ResponseTextFillIn{}
+			}
+			if question.NumFillIns == nil {
+				question.NumFillIns = []common.ResponseNumFillIn{}
+			}
+
+			switch question.Type {
+			case "SingleChoice", "MultiChoice": // 单选/多选题
+				for _, option := range question.Options {
+
+					responseOption := common.ResponseOption{
+						ResponseID:    response.ResponseID,
+						OptionID:      option.OptionID,
+						QuestionID:    question.QID,
+						SurveyID:      response.SurveyID,
+						OptionContent: option.OptionContent,
+						IsSelect:      option.IsSelect,
+					}
+					if option.IsSelect && openEnded[option.OptionID] {
+						responseOption.OtherText = strings.TrimSpace(option.OtherText)
+					}
+					if err := tx.Create(&responseOption).Error; err != nil {
+						return errors.New("failed to save response option: " + err.Error())
+					}
+				}
+			case "SingleTextFillIn", "MultiTextFillIn": // 单文本/多文本填空题
+				for _, textFillIn := range question.TextFillIns {
+					responseTextFillIn := common.ResponseTextFillIn{
+						ResponseID:   response.ResponseID,
+						TextFillInID: textFillIn.TextFillInID,
+						QuestionID:   question.QID,
+						SurveyID:     response.SurveyID,
+						TextContent:  textFillIn.TextContent,
+					}
+					if err := tx.Create(&responseTextFillIn).Error; err != nil {
+						return errors.New("failed to save text fill-in response: " + err.Error())
+					}
 				}
-				if err := common.DB.Create(&responseNumFillIn).Error; err != nil {
-					return errors.New("failed to save number fill-in response: " + err.Error())
+			case "SingleNumFillIn", "MultiNumFillIn": // 单数字/多数字填空题
+				for _, numFillIn := range question.NumFillIns {
+					responseNumFillIn := common.ResponseNumFillIn{
+						ResponseID:  response.ResponseID,
+						NumFillInID: numFillIn.NumFillInID,
+						QuestionID:  question.QID,
+						SurveyID:    response.SurveyID,
+						NumContent:  numFillIn.NumContent,
+					}
+					if err := tx.Create(&responseNumFillIn).Error; err != nil {
+						return errors.New("failed to save number fill-in response: " + err.Error())
+					}
 				}
+			default:
+				log.Printf("Unsupported question type: %s", question.Type)
+				continue // 跳过未知类型
 			}
-		default:
-			log.Printf("Unsupported question type: %s", question.Type)
-			continue // 跳过未知类型
 		}
+
+		// 更新问卷的 ResponseCount
+		if err := tx.Model(&survey).Update("ResponseCount", gorm.Expr("ResponseCount + 1")).Error; err != nil {
+			return errors.New("failed to update survey response count: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	// 更新问卷的 ResponseCount
-	if err := common.DB.Model(&survey).Update("ResponseCount", survey.ResponseCount+1).Error; err != nil {
-		return errors.New("failed to update survey response count: " + err.Error())
+// GetSurveyPreviewService 以答题者视角预览问卷草稿，仅问卷所有者可用
+func GetSurveyPreviewService(surveyId, userID string) (*SurveyModel, error) {
+	survey, err := getOwnedSurvey(surveyId, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return nil, err
+	}
+	model.IsOpening = IsSurveyOpen(survey)
+	return model, nil
 }
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"server/common"
+	"strconv"
+	"strings"
+)
+
+// exportColumn 导出表格中的一列
+type exportColumn struct {
+	header   string
+	question QuestionModel
+	kind     string // choice / other / text / num
+	fieldID  string // “其他”选项或填空 ID
+}
+
+// ExportSurveyResponses 将问卷的答卷导出为表格行，第一行为表头。
+// 选择题一列列出选中的选项，codes 为 true 时列出选项分值；“其他”选项的附带文本单独成列，
+// 每个填空各占一列，计算变量列在最后
+func ExportSurveyResponses(surveyID, userID string, filters []VariableFilter, codes bool) ([][]string, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := loadQuestionModels(common.DB, survey.SurveyID, survey.QuestionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 构造表头
+	columns := []exportColumn{}
+	for i, question := range questions {
+		title := strconv.Itoa(i+1) + ". " + question.Title
+		switch question.Type {
+		case "SingleChoice", "MultiChoice":
+			columns = append(columns, exportColumn{header: title, question: question, kind: "choice"})
+			for _, option := range question.Options {
+				if option.IsOpenEnded {
+					columns = append(columns, exportColumn{header: title + " - " + option.OptionContent, question: question, kind: "other", fieldID: option.OptionID})
+				}
+			}
+		case "SingleTextFillIn", "MultiTextFillIn":
+			for j, textFillIn := range question.TextFillIns {
+				header := title
+				if len(question.TextFillIns) > 1 {
+					header += " (" + strconv.Itoa(j+1) + ")"
+				}
+				columns = append(columns, exportColumn{header: header, question: question, kind: "text", fieldID: textFillIn.TextFillInID})
+			}
+		case "SingleNumFillIn", "MultiNumFillIn":
+			for j, numFillIn := range question.NumFillIns {
+				header := title
+				if len(question.NumFillIns) > 1 {
+					header += " (" + strconv.Itoa(j+1) + ")"
+				}
+				columns = append(columns, exportColumn{header: header, question: question, kind: "num", fieldID: numFillIn.NumFillInID})
+			}
+		}
+	}
+
+	variables := decodeVariables(survey.Variables)
+
+	header := []string{"ResponseID", "SurveyVersion", "Language"}
+	for _, column := range columns {
+		header = append(header, column.header)
+	}
+	for _, variable := range variables {
+		header = append(header, variable.Name)
+	}
+	rows := [][]string{header}
+
+	var responses []common.SurveyResponse
+	query := applyVariableFilters(common.DB.Where("SurveyID = ?", surveyID), surveyID, filters)
+	if err := query.Find(&responses).Error; err != nil {
+		return nil, errors.New("failed to retrieve responses")
+	}
+	responseIDs := []string{}
+	for _, response := range responses {
+		responseIDs = append(responseIDs, response.ResponseID)
+	}
+	variableValues, err := loadResponseVariables(surveyID, responseIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		var options []common.ResponseOption
+		var texts []common.ResponseTextFillIn
+		var nums []common.ResponseNumFillIn
+		if err := common.DB.Where("ResponseID = ? AND IsSelect = ?", response.ResponseID, true).Find(&options).Error; err != nil {
+			return nil, errors.New("failed to retrieve response options")
+		}
+		if err := common.DB.Where("ResponseID = ?", response.ResponseID).Find(&texts).Error; err != nil {
+			return nil, errors.New("failed to retrieve text answers")
+		}
+		if err := common.DB.Where("ResponseID = ?", response.ResponseID).Find(&nums).Error; err != nil {
+			return nil, errors.New("failed to retrieve number answers")
+		}
+
+		selected := map[string]common.ResponseOption{}
+		for _, option := range options {
+			selected[option.OptionID] = option
+		}
+		textValues := map[string]string{}
+		for _, text := range texts {
+			textValues[text.TextFillInID] = text.TextContent
+		}
+		numValues := map[string]string{}
+		for _, num := range nums {
+			numValues[num.NumFillInID] = strconv.FormatFloat(num.NumContent, 'f', -1, 64)
+		}
+
+		// 选项内容使用答题者实际看到的文本
+		rendered := decodeRenderedText(response.RenderedText)
+
+		row := []string{response.ResponseID, strconv.Itoa(response.SurveyVersion), response.Language}
+		for _, column := range columns {
+			value := ""
+			switch column.kind {
+			case "choice":
+				contents := []string{}
+				for i, option := range column.question.Options {
+					if _, ok := selected[option.OptionID]; !ok {
+						continue
+					}
+					if codes {
+						contents = append(contents, strconv.FormatFloat(optionValue(option, i), 'f', -1, 64))
+					} else if content, ok := rendered[column.question.QuestionID].Options[option.OptionID]; ok {
+						contents = append(contents, content)
+					} else {
+						contents = append(contents, option.OptionContent)
+					}
+				}
+				value = csvSafe(strings.Join(contents, "; "))
+			case "other":
+				value = csvSafe(selected[column.fieldID].OtherText)
+			case "text":
+				value = csvSafe(textValues[column.fieldID])
+			case "num":
+				value = numValues[column.fieldID]
+			}
+			row = append(row, value)
+		}
+		for _, variable := range variables {
+			value := ""
+			if number, ok := variableValues[response.ResponseID][variable.Name]; ok {
+				value = strconv.FormatFloat(number, 'f', -1, 64)
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvSafe 防止 CSV 在 Excel 中打开时文本被当作公式执行，以公式字符开头的文本前加单引号
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"server/common"
+	"sort"
 )
 
 // GetOptionCount 获取选项被选择的数量
@@ -24,6 +26,98 @@ func GetOptionCount(surveyID, optionID string) (int64, error) {
 	return count, nil
 }
 
+// ChoiceOptionStats 选项的分值和选择次数
+type ChoiceOptionStats struct {
+	OptionID      string  `json:"OptionID"`
+	OptionContent string  `json:"OptionContent"`
+	OptionValue   float64 `json:"OptionValue"`
+	Count         int     `json:"Count"`
+}
+
+// ChoiceStats 选择题的统计结果，均值和中位数按每份答卷选中选项的分值计算，多选题取分值之和
+type ChoiceStats struct {
+	QuestionID    string              `json:"QuestionID"`
+	ResponseCount int                 `json:"ResponseCount"` // 作答该题的答卷数
+	Mean          float64             `json:"Mean"`
+	Median        float64             `json:"Median"`
+	Options       []ChoiceOptionStats `json:"Options"`
+}
+
+// GetChoiceStats 获取已发布选择题的选项分布和分值统计
+func GetChoiceStats(surveyID, userID, questionID string) (*ChoiceStats, error) {
+	// 仅问卷所有者可以查看统计
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只统计已发布的题目
+	published := false
+	for _, id := range splitIDs(survey.QuestionIDs) {
+		if id == questionID {
+			published = true
+		}
+	}
+	if !published {
+		return nil, errors.New("question not found")
+	}
+	questions, err := loadQuestionModels(common.DB, surveyID, questionID)
+	if err != nil {
+		return nil, err
+	}
+	question := questions[0]
+	if question.Type != "SingleChoice" && question.Type != "MultiChoice" {
+		return nil, errors.New("question is not a choice question")
+	}
+
+	var selections []common.ResponseOption
+	if err := common.DB.Where("SurveyID = ? AND QuestionID = ? AND IsSelect = ?", surveyID, questionID, true).
+		Find(&selections).Error; err != nil {
+		return nil, errors.New("failed to retrieve response options")
+	}
+
+	// 按答卷累计分值，已删除的选项不计入
+	index := map[string]int{}
+	stats := &ChoiceStats{QuestionID: questionID, Options: []ChoiceOptionStats{}}
+	for i, option := range question.Options {
+		index[option.OptionID] = i
+		stats.Options = append(stats.Options, ChoiceOptionStats{
+			OptionID:      option.OptionID,
+			OptionContent: option.OptionContent,
+			OptionValue:   optionValue(option, i),
+		})
+	}
+	values := map[string]float64{}
+	for _, selection := range selections {
+		i, ok := index[selection.OptionID]
+		if !ok {
+			continue
+		}
+		stats.Options[i].Count++
+		values[selection.ResponseID] += stats.Options[i].OptionValue
+	}
+
+	list := []float64{}
+	for _, value := range values {
+		list = append(list, value)
+	}
+	if len(list) > 0 {
+		sort.Float64s(list)
+		sum := 0.0
+		for _, value := range list {
+			sum += value
+		}
+		stats.ResponseCount = len(list)
+		stats.Mean = sum / float64(len(list))
+		if len(list)%2 == 1 {
+			stats.Median = list[len(list)/2]
+		} else {
+			stats.Median = (list[len(list)/2-1] + list[len(list)/2]) / 2
+		}
+	}
+	return stats, nil
+}
+
 // GetTextFillinData 获取指定填空题的所有回答
 func GetTextFillinData(surveyID, textFillinID string) ([]string, error) {
 	// 验证问卷是否存在
@@ -56,7 +150,7 @@ func GetTextFillinData(surveyID, textFillinID string) ([]string, error) {
 }
 
 // GetNumFillinData 获取指定数字填空题的所有数值回答
-func GetNumFillinData(surveyID, numFillInID string) ([]int, error) {
+func GetNumFillinData(surveyID, numFillInID string) ([]float64, error) {
 	// 验证问卷是否存在
 	var survey common.Survey
 	if err := common.DB.Where("SurveyID = ?", surveyID).First(&survey).Error; err != nil {
@@ -78,7 +172,7 @@ func GetNumFillinData(surveyID, numFillInID string) ([]int, error) {
 	}
 
 	// 提取数值回答
-	var numbers []int
+	var numbers []float64
 	for _, response := range responses {
 		numbers = append(numbers, response.NumContent)
 	}
@@ -86,11 +180,69 @@ func GetNumFillinData(surveyID, numFillInID string) ([]int, error) {
 	return numbers, nil
 }
 
+// OtherTextAnswer “其他”选项收集到的文本
+type OtherTextAnswer struct {
+	ResponseID string `json:"ResponseID"`
+	OtherText  string `json:"OtherText"`
+}
+
+// OtherTextGroup 单个“其他”选项收集到的所有文本
+type OtherTextGroup struct {
+	QuestionID    string            `json:"QuestionID"`
+	OptionID      string            `json:"OptionID"`
+	OptionContent string            `json:"OptionContent"`
+	Texts         []OtherTextAnswer `json:"Texts"`
+}
+
+// GetOtherTexts 按选项列出问卷中“其他”选项收集到的文本，questionID 为空时列出所有题目，仅问卷所有者可用
+func GetOtherTexts(surveyID, userID, questionID string) ([]OtherTextGroup, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
+	}
+
+	db := common.DB.Where("SurveyID = ? AND IsOpenEnded = ?", surveyID, true)
+	if questionID != "" {
+		db = db.Where("QuestionID = ?", questionID)
+	}
+	var options []common.QuestionOption
+	if err := db.Find(&options).Error; err != nil {
+		return nil, errors.New("failed to retrieve options")
+	}
+
+	groups := []OtherTextGroup{}
+	for _, option := range options {
+		group := OtherTextGroup{
+			QuestionID:    option.QuestionID,
+			OptionID:      option.OptionID,
+			OptionContent: option.OptionContent,
+			Texts:         []OtherTextAnswer{},
+		}
+		err := common.DB.Model(&common.ResponseOption{}).
+			Select("ResponseID AS response_id, OtherText AS other_text").
+			Where("SurveyID = ? AND OptionID = ? AND IsSelect = ? AND OtherText <> ?", surveyID, option.OptionID, true, "").
+			Scan(&group.Texts).Error
+		if err != nil {
+			return nil, errors.New("failed to retrieve other texts")
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
 // ResponseDetailModel 答卷详情返回模型
 type ResponseDetailModel struct {
-	ResponseID string           `json:"ResponseID"`
-	SurveyID   string           `json:"SurveyID"`
-	Questions  []QuestionDetail `json:"QuestionResponse"`
+	ResponseID    string           `json:"ResponseID"`
+	SurveyID      string           `json:"SurveyID"`
+	SurveyVersion int              `json:"SurveyVersion"`
+	Questions     []QuestionDetail `json:"QuestionResponse"`
+
+	PresentationOrder *PresentationOrder `json:"PresentationOrder,omitempty"` // 展示给答题者的顺序
+
+	Score    *float64 `json:"Score,omitempty"` // 测验得分，非测验答卷为空
+	MaxScore float64  `json:"MaxScore,omitempty"`
+
+	Variables map[string]float64 `json:"Variables,omitempty"` // 计算变量的值
+	Language  string             `json:"Language,omitempty"`  // 答题语言
 }
 
 type QuestionDetail struct {
@@ -110,6 +262,7 @@ type OptionDetail struct {
 	OptionContent string `json:"OptionContent"`
 	QuestionID    string `json:"QuestionID"`
 	IsSelect      bool   `json:"IsSelect"`
+	OtherText     string `json:"OtherText"` // “其他”选项附带的文本
 }
 
 type ResponseTextFillInData struct {
@@ -120,25 +273,32 @@ type ResponseTextFillInData struct {
 }
 
 type ResponseNumFillInData struct {
-	ResponseID  string `json:"ResponseID"`
-	NumFillInID string `json:"NumFillInID"`
-	QuestionID  string `json:"QuestionID"`
-	NumContent  int    `json:"NumContent"`
+	ResponseID  string  `json:"ResponseID"`
+	NumFillInID string  `json:"NumFillInID"`
+	QuestionID  string  `json:"QuestionID"`
+	NumContent  float64 `json:"NumContent"`
 }
 
-// GetSurveyResponses 获取指定问卷的所有答卷内容
-func GetSurveyResponses(surveyID string) ([]ResponseDetailModel, error) {
-	// 验证问卷是否存在
-	var survey common.Survey
-	if err := common.DB.Where("SurveyID = ?", surveyID).First(&survey).Error; err != nil {
-		return nil, errors.New("survey not found")
+// GetSurveyResponses 获取指定问卷的所有答卷内容，仅问卷所有者可查看
+func GetSurveyResponses(surveyID, userID string, filters []VariableFilter) ([]ResponseDetailModel, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
 	}
 
 	// 获取该问卷下的所有答卷
 	var responses []common.SurveyResponse
-	if err := common.DB.Where("SurveyID = ?", surveyID).Find(&responses).Error; err != nil {
+	query := applyVariableFilters(common.DB.Where("SurveyID = ?", surveyID), surveyID, filters)
+	if err := query.Find(&responses).Error; err != nil {
 		return nil, errors.New("failed to retrieve responses")
 	}
+	responseIDs := []string{}
+	for _, response := range responses {
+		responseIDs = append(responseIDs, response.ResponseID)
+	}
+	variables, err := loadResponseVariables(surveyID, responseIDs)
+	if err != nil {
+		return nil, err
+	}
 
 	// 构建返回结果
 	var responseDetails []ResponseDetailModel
@@ -149,7 +309,8 @@ func GetSurveyResponses(surveyID string) ([]ResponseDetailModel, error) {
 			return nil, errors.New("failed to retrieve questions")
 		}
 
-		// 构建问题详情
+		// 构建问题详情，含占位符的题目使用答题者实际看到的文本
+		rendered := decodeRenderedText(response.RenderedText)
 		var questionDetails []QuestionDetail
 		for _, question := range questions {
 			questionDetail := QuestionDetail{
@@ -159,18 +320,27 @@ func GetSurveyResponses(surveyID string) ([]ResponseDetailModel, error) {
 				Description:  question.Description,
 				QuestionType: question.QuestionType,
 			}
+			renderedQuestion, hasRendered := rendered[question.QuestionID]
+			if hasRendered {
+				questionDetail.Title = renderedQuestion.Title
+				questionDetail.Description = renderedQuestion.Description
+			}
 
 			switch question.QuestionType {
 			case "SingleChoice", "MultiChoice": // 单选/多选
 				var options []common.ResponseOption
 				if err := common.DB.Where("QuestionID = ? AND ResponseID = ?", question.QuestionID, response.ResponseID).Find(&options).Error; err == nil {
 					for _, option := range options {
+						if content, ok := renderedQuestion.Options[option.OptionID]; ok {
+							option.OptionContent = content
+						}
 						questionDetail.Options = append(questionDetail.Options, OptionDetail{
 							ResponseID:    option.ResponseID,
 							OptionID:      option.OptionID,
 							OptionContent: option.OptionContent,
 							QuestionID:    option.QuestionID,
 							IsSelect:      option.IsSelect,
+							OtherText:     option.OtherText,
 						})
 					}
 				}
@@ -214,11 +384,23 @@ func GetSurveyResponses(surveyID string) ([]ResponseDetailModel, error) {
 		}
 
 		// 构建每个答卷的模型
-		responseDetails = append(responseDetails, ResponseDetailModel{
-			ResponseID: response.ResponseID,
-			SurveyID:   surveyID,
-			Questions:  questionDetails,
-		})
+		detail := ResponseDetailModel{
+			ResponseID:    response.ResponseID,
+			SurveyID:      surveyID,
+			SurveyVersion: response.SurveyVersion,
+			Questions:     questionDetails,
+			Score:         response.Score,
+			MaxScore:      response.MaxScore,
+			Variables:     variables[response.ResponseID],
+			Language:      response.Language,
+		}
+		if response.PresentationOrder != "" {
+			var order PresentationOrder
+			if err := json.Unmarshal([]byte(response.PresentationOrder), &order); err == nil {
+				detail.PresentationOrder = &order
+			}
+		}
+		responseDetails = append(responseDetails, detail)
 	}
 
 	return responseDetails, nil
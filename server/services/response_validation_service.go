@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError 答卷中单个题目或填空的校验错误
+type FieldError struct {
+	QuestionID string `json:"questionId"`
+	FieldID    string `json:"fieldId,omitempty"` // 选项或填空 ID，题目级错误为空
+	Message    string `json:"message"`
+}
+
+// ResponseValidationError 答卷校验失败，包含所有字段错误
+type ResponseValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ResponseValidationError) Error() string {
+	return fmt.Sprintf("response validation failed with %d error(s)", len(e.Errors))
+}
+
+// validateQuestionRules 校验编辑时设置的题目规则本身是否合法，path 为错误信息中的字段路径前缀
+func validateQuestionRules(path string, questions []QuestionModel) []string {
+	errs := []string{}
+	for i, question := range questions {
+		prefix := fmt.Sprintf("%s[%d]", path, i)
+		errs = append(errs, validatePipingPlaceholders(prefix, i, question)...)
+		if !validImageURL(question.Image) {
+			errs = append(errs, prefix+".Image: must be an http(s) URL or a site path")
+		}
+		correct := 0
+		for j, option := range question.Options {
+			if !validImageURL(option.Image) {
+				errs = append(errs, fmt.Sprintf("%s.Options[%d].Image: must be an http(s) URL or a site path", prefix, j))
+			}
+			if option.IsCorrect {
+				correct++
+			}
+		}
+		// 单选题只能选中一项，多个正确选项时无法得分
+		if question.Type == "SingleChoice" && correct > 1 {
+			errs = append(errs, prefix+".Options: a single choice question can have at most one correct option")
+		}
+		for j, textFillIn := range question.TextFillIns {
+			field := fmt.Sprintf("%s.TextFillIns[%d]", prefix, j)
+			if textFillIn.MinLength < 0 || textFillIn.MaxLength < 0 {
+				errs = append(errs, field+".MinLength: length limits must not be negative")
+			} else if textFillIn.MaxLength > 0 && textFillIn.MinLength > textFillIn.MaxLength {
+				errs = append(errs, field+".MinLength: must not exceed MaxLength")
+			}
+			if textFillIn.Pattern != "" {
+				if _, err := regexp.Compile(textFillIn.Pattern); err != nil {
+					errs = append(errs, field+".Pattern: invalid regular expression")
+				}
+			}
+		}
+		for j, numFillIn := range question.NumFillIns {
+			field := fmt.Sprintf("%s.NumFillIns[%d]", prefix, j)
+			if numFillIn.MinValue != nil && numFillIn.MaxValue != nil && *numFillIn.MinValue > *numFillIn.MaxValue {
+				errs = append(errs, field+".MinValue: must not exceed MaxValue")
+			}
+		}
+	}
+	return errs
+}
+
+// validateResponse 按已发布题目的规则校验答卷，返回所有字段错误
+func validateResponse(questions []QuestionModel, response *ResponseModel) []FieldError {
+	errs := []FieldError{}
+	answers := map[string]*QuestionResponseModel{}
+	for i := range response.QuestionsResponse {
+		answer := &response.QuestionsResponse[i]
+		answers[answer.QID] = answer
+	}
+
+	known := map[string]bool{}
+	for _, question := range questions {
+		known[question.QuestionID] = true
+		answer := answers[question.QuestionID]
+		if answer != nil && answer.Type != "" && answer.Type != question.Type {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, Message: "question type mismatch"})
+			continue
+		}
+
+		var questionErrs []FieldError
+		answered := false
+		switch question.Type {
+		case "SingleChoice", "MultiChoice":
+			answered, questionErrs = validateChoiceAnswer(question, answer)
+		case "SingleTextFillIn", "MultiTextFillIn":
+			answered, questionErrs = validateTextAnswer(question, answer)
+		case "SingleNumFillIn", "MultiNumFillIn":
+			answered, questionErrs = validateNumAnswer(question, answer)
+		}
+		if !answered && question.Required {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, Message: messageOr(question.RequiredMessage, "this question is required")})
+			continue
+		}
+		errs = append(errs, questionErrs...)
+	}
+
+	for _, answer := range response.QuestionsResponse {
+		if !known[answer.QID] {
+			errs = append(errs, FieldError{QuestionID: answer.QID, Message: "question does not belong to this survey"})
+		}
+	}
+	return errs
+}
+
+// validateChoiceAnswer 校验选择题的选项和选择数量
+func validateChoiceAnswer(question QuestionModel, answer *QuestionResponseModel) (bool, []FieldError) {
+	if answer == nil {
+		return false, nil
+	}
+	errs := []FieldError{}
+	options := map[string]int{}
+	for i, option := range question.Options {
+		options[option.OptionID] = i
+	}
+
+	selected, exclusive := 0, false
+	for _, option := range answer.Options {
+		i, ok := options[option.OptionID]
+		if !ok {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, FieldID: option.OptionID, Message: "unknown option"})
+			continue
+		}
+		if !option.IsSelect {
+			continue
+		}
+		selected++
+		if question.Options[i].IsExclusive {
+			exclusive = true
+		}
+
+		// “其他”选项选中时必须填写文本
+		if question.Options[i].IsOpenEnded && strings.TrimSpace(option.OtherText) == "" {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, FieldID: option.OptionID, Message: "please specify"})
+		}
+	}
+	if selected == 0 {
+		return false, errs
+	}
+
+	if question.Type == "SingleChoice" && selected > 1 {
+		errs = append(errs, FieldError{QuestionID: question.QuestionID, Message: "only one option can be selected"})
+	} else if exclusive && selected > 1 {
+		errs = append(errs, FieldError{QuestionID: question.QuestionID, Message: "exclusive option cannot be combined with other options"})
+	} else if !exclusive && question.LeastChoice > 0 && selected < question.LeastChoice {
+		errs = append(errs, FieldError{QuestionID: question.QuestionID, Message: fmt.Sprintf("select at least %d option(s)", question.LeastChoice)})
+	} else if question.MaxChoice > 0 && selected > question.MaxChoice {
+		errs = append(errs, FieldError{QuestionID: question.QuestionID, Message: fmt.Sprintf("select at most %d option(s)", question.MaxChoice)})
+	}
+	return true, errs
+}
+
+// validateTextAnswer 校验文本填空的长度和格式，空白内容视为未作答
+func validateTextAnswer(question QuestionModel, answer *QuestionResponseModel) (bool, []FieldError) {
+	if answer == nil {
+		return false, nil
+	}
+	errs := []FieldError{}
+	rules := map[string]int{}
+	for i, textFillIn := range question.TextFillIns {
+		rules[textFillIn.TextFillInID] = i
+	}
+
+	answered := false
+	for _, textFillIn := range answer.TextFillIns {
+		i, ok := rules[textFillIn.TextFillInID]
+		if !ok {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, FieldID: textFillIn.TextFillInID, Message: "unknown fill-in"})
+			continue
+		}
+		content := strings.TrimSpace(textFillIn.TextContent)
+		if content == "" {
+			continue
+		}
+		answered = true
+
+		rule := question.TextFillIns[i]
+		message := ""
+		length := utf8.RuneCountInString(content)
+		switch {
+		case rule.MinLength > 0 && length < rule.MinLength:
+			message = fmt.Sprintf("must be at least %d characters", rule.MinLength)
+		case rule.MaxLength > 0 && length > rule.MaxLength:
+			message = fmt.Sprintf("must be at most %d characters", rule.MaxLength)
+		case rule.Pattern != "":
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err == nil && !pattern.MatchString(content) {
+				message = "invalid format"
+			}
+		}
+		if message != "" {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, FieldID: rule.TextFillInID, Message: messageOr(rule.ErrorMessage, message)})
+		}
+	}
+	return answered, errs
+}
+
+// validateNumAnswer 校验数字填空的范围和是否为整数
+func validateNumAnswer(question QuestionModel, answer *QuestionResponseModel) (bool, []FieldError) {
+	if answer == nil {
+		return false, nil
+	}
+	errs := []FieldError{}
+	rules := map[string]int{}
+	for i, numFillIn := range question.NumFillIns {
+		rules[numFillIn.NumFillInID] = i
+	}
+
+	answered := false
+	for _, numFillIn := range answer.NumFillIns {
+		i, ok := rules[numFillIn.NumFillInID]
+		if !ok {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, FieldID: numFillIn.NumFillInID, Message: "unknown fill-in"})
+			continue
+		}
+		answered = true
+
+		rule := question.NumFillIns[i]
+		value := numFillIn.NumContent
+		message := ""
+		switch {
+		case math.IsNaN(value) || math.IsInf(value, 0):
+			message = "must be a number"
+		case rule.IntegerOnly && value != math.Trunc(value):
+			message = "must be an integer"
+		case rule.MinValue != nil && value < *rule.MinValue:
+			message = fmt.Sprintf("must be at least %g", *rule.MinValue)
+		case rule.MaxValue != nil && value > *rule.MaxValue:
+			message = fmt.Sprintf("must be at most %g", *rule.MaxValue)
+		}
+		if message != "" {
+			errs = append(errs, FieldError{QuestionID: question.QuestionID, FieldID: rule.NumFillInID, Message: messageOr(rule.ErrorMessage, message)})
+		}
+	}
+	return answered, errs
+}
+
+// messageOr 优先使用题目设置的自定义提示
+func messageOr(custom, fallback string) string {
+	if strings.TrimSpace(custom) != "" {
+		return custom
+	}
+	return fallback
+}
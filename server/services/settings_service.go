@@ -0,0 +1,267 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"server/common"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 外观和答题设置的长度限制
+const (
+	maxImageURLLength   = 1024
+	maxFooterLength     = 2000
+	maxSettingsTextSize = 5000
+)
+
+// SurveyAppearance 问卷外观设置
+type SurveyAppearance struct {
+	ThemeColor        int     `json:"themeColor"`
+	TextColor         int     `json:"textColor"`
+	PCBackgroundImage string  `json:"pcBackgroundImage"`
+	PCBannerImage     string  `json:"pcBannerImage"`
+	Footer            *string `json:"footer"`
+	DisplayStyle      int     `json:"displayStyle"`
+	ButtonText        *string `json:"buttonText"` // JSON 文本
+}
+
+// SurveyBehavior 问卷答题设置，不包含访问密码
+type SurveyBehavior struct {
+	StartTime        time.Time `json:"startTime"`
+	EndTime          time.Time `json:"endTime"`
+	DayStartTime     time.Time `json:"dayStartTime"`
+	DayEndTime       time.Time `json:"dayEndTime"`
+	MaxResponseCount int       `json:"maxResponseCount"`
+	BrowserLimit     bool      `json:"browserLimit"`
+	IPLimit          bool      `json:"ipLimit"`
+	KeepContent      bool      `json:"keepContent"`
+	FailMessage      string    `json:"failMessage"`
+	ShowAfterSubmit  int       `json:"showAfterSubmit"`
+	ShowContent      string    `json:"showContent"`
+}
+
+// SurveySettings 问卷外观和答题设置
+type SurveySettings struct {
+	Appearance SurveyAppearance `json:"appearance"`
+	Settings   SurveyBehavior   `json:"settings"`
+	Revision   int              `json:"-"` // 修订号，通过 ETag 返回
+}
+
+// SurveySettingsUpdate 修改设置的请求，未提供的部分保持不变
+type SurveySettingsUpdate struct {
+	Appearance *SurveyAppearance `json:"appearance"`
+	Settings   *SurveyBehavior   `json:"settings"`
+}
+
+// SurveySettingsError 设置校验失败，包含所有错误
+type SurveySettingsError struct {
+	Errors []string
+}
+
+func (e *SurveySettingsError) Error() string {
+	return "invalid survey settings: " + strings.Join(e.Errors, "; ")
+}
+
+// appearanceOf 读取问卷的外观设置
+func appearanceOf(survey *common.Survey) SurveyAppearance {
+	return SurveyAppearance{
+		ThemeColor:        survey.ThemeColor,
+		TextColor:         survey.TextColor,
+		PCBackgroundImage: survey.PCBackgroundImage,
+		PCBannerImage:     survey.PCBannerImage,
+		Footer:            survey.Footer,
+		DisplayStyle:      survey.DisplayStyle,
+		ButtonText:        survey.ButtonText,
+	}
+}
+
+// behaviorOf 读取问卷的答题设置
+func behaviorOf(survey *common.Survey) SurveyBehavior {
+	return SurveyBehavior{
+		StartTime:        survey.StartTime,
+		EndTime:          survey.EndTime,
+		DayStartTime:     survey.DayStartTime,
+		DayEndTime:       survey.DayEndTime,
+		MaxResponseCount: survey.MaxResponseCount,
+		BrowserLimit:     survey.BrowserLimit,
+		IPLimit:          survey.IPLimit,
+		KeepContent:      survey.KeepContent,
+		FailMessage:      survey.FailMessage,
+		ShowAfterSubmit:  survey.ShowAfterSubmit,
+		ShowContent:      survey.ShowContent,
+	}
+}
+
+// validImageURL 图片地址须为空、http(s) 地址或站内路径
+func validImageURL(url string) bool {
+	if url == "" {
+		return true
+	}
+	if len(url) > maxImageURLLength || strings.ContainsAny(url, " \t\r\n\"'<>") {
+		return false
+	}
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") ||
+		strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//")
+}
+
+// validateAppearance 校验外观设置，path 为错误信息中的字段路径前缀
+func validateAppearance(path string, appearance SurveyAppearance) []string {
+	errs := []string{}
+	if appearance.ThemeColor < 0 {
+		errs = append(errs, path+".themeColor: must not be negative")
+	}
+	if appearance.TextColor < 0 {
+		errs = append(errs, path+".textColor: must not be negative")
+	}
+	if appearance.DisplayStyle < 0 {
+		errs = append(errs, path+".displayStyle: must not be negative")
+	}
+	if !validImageURL(appearance.PCBackgroundImage) {
+		errs = append(errs, path+".pcBackgroundImage: must be an http(s) URL or a site path")
+	}
+	if !validImageURL(appearance.PCBannerImage) {
+		errs = append(errs, path+".pcBannerImage: must be an http(s) URL or a site path")
+	}
+	if appearance.Footer != nil && utf8.RuneCountInString(*appearance.Footer) > maxFooterLength {
+		errs = append(errs, path+".footer: is too long")
+	}
+	// ButtonText 以 JSON 类型存储
+	if appearance.ButtonText != nil && !json.Valid([]byte(*appearance.ButtonText)) {
+		errs = append(errs, path+".buttonText: must be valid JSON")
+	}
+	return errs
+}
+
+// validateBehavior 校验答题设置，path 为错误信息中的字段路径前缀
+func validateBehavior(path string, behavior SurveyBehavior) []string {
+	errs := []string{}
+	if behavior.MaxResponseCount < 0 {
+		errs = append(errs, path+".maxResponseCount: must not be negative")
+	}
+	if !behavior.StartTime.IsZero() && !behavior.EndTime.IsZero() && behavior.EndTime.Before(behavior.StartTime) {
+		errs = append(errs, path+".endTime: must be after startTime")
+	}
+	if behavior.ShowAfterSubmit < 0 {
+		errs = append(errs, path+".showAfterSubmit: must not be negative")
+	}
+	if utf8.RuneCountInString(behavior.FailMessage) > maxSettingsTextSize {
+		errs = append(errs, path+".failMessage: is too long")
+	}
+	if utf8.RuneCountInString(behavior.ShowContent) > maxSettingsTextSize {
+		errs = append(errs, path+".showContent: is too long")
+	}
+	return errs
+}
+
+// applyAppearance 将外观设置写入问卷
+func applyAppearance(survey *common.Survey, appearance SurveyAppearance) {
+	survey.ThemeColor = appearance.ThemeColor
+	survey.TextColor = appearance.TextColor
+	survey.PCBackgroundImage = appearance.PCBackgroundImage
+	survey.PCBannerImage = appearance.PCBannerImage
+	survey.Footer = appearance.Footer
+	survey.DisplayStyle = appearance.DisplayStyle
+	survey.ButtonText = appearance.ButtonText
+}
+
+// applyBehavior 将答题设置写入问卷，未指定的时间保留原值
+func applyBehavior(survey *common.Survey, behavior SurveyBehavior) {
+	if !behavior.StartTime.IsZero() {
+		survey.StartTime = behavior.StartTime
+	}
+	if !behavior.EndTime.IsZero() {
+		survey.EndTime = behavior.EndTime
+	}
+	if !behavior.DayStartTime.IsZero() {
+		survey.DayStartTime = behavior.DayStartTime
+	}
+	if !behavior.DayEndTime.IsZero() {
+		survey.DayEndTime = behavior.DayEndTime
+	}
+	survey.MaxResponseCount = behavior.MaxResponseCount
+	survey.BrowserLimit = behavior.BrowserLimit
+	survey.IPLimit = behavior.IPLimit
+	survey.KeepContent = behavior.KeepContent
+	survey.FailMessage = behavior.FailMessage
+	survey.ShowAfterSubmit = behavior.ShowAfterSubmit
+	survey.ShowContent = behavior.ShowContent
+}
+
+// GetSurveySettings 获取问卷的外观和答题设置
+func GetSurveySettings(surveyID, userID string) (*SurveySettings, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &SurveySettings{
+		Appearance: appearanceOf(survey),
+		Settings:   behaviorOf(survey),
+		Revision:   survey.Revision,
+	}, nil
+}
+
+// UpdateSurveySettings 修改问卷的外观和答题设置，设置立即对答题者生效，只写入请求中提供的部分。
+// expectedRevision 与当前修订号不一致时返回 ErrRevisionConflict
+func UpdateSurveySettings(surveyID, userID string, expectedRevision int, update SurveySettingsUpdate) (*SurveySettings, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Appearance == nil && update.Settings == nil {
+		return nil, errors.New("nothing to update")
+	}
+
+	errs := []string{}
+	columns := map[string]interface{}{}
+	if update.Appearance != nil {
+		errs = append(errs, validateAppearance("appearance", *update.Appearance)...)
+		applyAppearance(survey, *update.Appearance)
+		columns["ThemeColor"] = survey.ThemeColor
+		columns["TextColor"] = survey.TextColor
+		columns["PCBackgroundImage"] = survey.PCBackgroundImage
+		columns["PCBannerImage"] = survey.PCBannerImage
+		columns["Footer"] = survey.Footer
+		columns["DisplayStyle"] = survey.DisplayStyle
+		columns["ButtonText"] = survey.ButtonText
+	}
+	if update.Settings != nil {
+		applyBehavior(survey, *update.Settings)
+		errs = append(errs, validateBehavior("settings", behaviorOf(survey))...)
+		columns["StartTime"] = survey.StartTime
+		columns["EndTime"] = survey.EndTime
+		columns["DayStartTime"] = survey.DayStartTime
+		columns["DayEndTime"] = survey.DayEndTime
+		columns["MaxResponseCount"] = survey.MaxResponseCount
+		columns["BrowserLimit"] = survey.BrowserLimit
+		columns["IPLimit"] = survey.IPLimit
+		columns["KeepContent"] = survey.KeepContent
+		columns["FailMessage"] = survey.FailMessage
+		columns["ShowAfterSubmit"] = survey.ShowAfterSubmit
+		columns["ShowContent"] = survey.ShowContent
+	}
+	if len(errs) > 0 {
+		return nil, &SurveySettingsError{Errors: errs}
+	}
+
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := touchSurvey(tx, survey, userID, expectedRevision); err != nil {
+			return err
+		}
+		if err := tx.Model(survey).Updates(columns).Error; err != nil {
+			return errors.New("failed to update survey settings")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SurveySettings{
+		Appearance: appearanceOf(survey),
+		Settings:   behaviorOf(survey),
+		Revision:   survey.Revision,
+	}, nil
+}
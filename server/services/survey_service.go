@@ -1,9 +1,16 @@
 package services
 
 import (
+	crand "crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"regexp"
+
 	// "fmt"
 	"server/common"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +19,44 @@ import (
 	"gorm.io/gorm"
 )
 
+// NewDefaultSurvey 构造带默认设置的新问卷
+func NewDefaultSurvey(title string) common.Survey {
+	now := time.Now()
+	return common.Survey{
+		SurveyID:          uuid.New().String(),
+		AccessID:          "", // 由 CreateSurvey 生成唯一访问 ID
+		Title:             title,
+		Description:       "", // 默认描述为空字符串，可以根据需要调整
+		CreateTime:        now,
+		ExpireTime:        now.AddDate(0, 1, 0), // 默认过期时间为 1 个月后
+		LastUpdateTime:    now,
+		Status:            StatusDraft,
+		ResponseCount:     0,                                                                            // 初始响应数量为 0
+		ThemeColor:        0,                                                                            // 默认主题颜色
+		TextColor:         0,                                                                            // 默认文字颜色
+		PCBackgroundImage: "",                                                                           // 默认背景图片为空
+		PCBannerImage:     "",                                                                           // 默认横幅图片为空
+		Footer:            nil,                                                                          // 页脚默认值为空
+		DisplayStyle:      0,                                                                            // 默认显示样式
+		ButtonText:        nil,                                                                          // 按钮文字默认值为空
+		StartTime:         now,                                                                          // 默认开始时间为当前时间
+		EndTime:           now.AddDate(0, 1, 0),                                                         // 默认结束时间为 1 个月后
+		DayStartTime:      time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),    // 默认每日开始时间为 00:00
+		DayEndTime:        time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, now.Location()), // 默认每日结束时间为 23:59
+		PasswordStrategy:  0,                                                                            // 默认密码策略
+		Password:          "{}",                                                                         // 默认无密码
+		MaxResponseCount:  0,                                                                            // 默认无限制
+		BrowserLimit:      false,                                                                        // 默认不限制浏览器
+		IPLimit:           false,                                                                        // 默认不限制 IP
+		KeepContent:       false,                                                                        // 默认不保留内容
+		FailMessage:       "",                                                                           // 默认失败消息为空
+		ShowAfterSubmit:   0,                                                                            // 默认提交后不显示内容
+		ShowContent:       "",                                                                           // 默认显示内容为空
+		QuestionIDs:       "{}",                                                                         // 默认无问题列表
+		ResponseIDs:       nil,                                                                          // 默认无响应列表
+	}
+}
+
 func CreateSurvey(userID string, survey *common.Survey) error {
 
 	var user common.User
@@ -20,14 +65,29 @@ func CreateSurvey(userID string, survey *common.Survey) error {
 		return result.Error
 	}
 
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		return insertSurvey(tx, userID, survey)
+	})
+}
+
+// insertSurvey 在事务中保存新问卷：设置所有者、生成唯一访问 ID 并记录初始状态
+func insertSurvey(tx *gorm.DB, userID string, survey *common.Survey) error {
 	survey.UserID = userID
+	survey.LastUpdateUser = userID
 
-	result = common.DB.Create(survey)
-	if result.Error != nil {
-		return result.Error
+	// 生成唯一访问 ID
+	if survey.AccessID == "" {
+		accessID, err := generateAccessID(tx)
+		if err != nil {
+			return err
+		}
+		survey.AccessID = accessID
 	}
 
-	return nil
+	if err := tx.Create(survey).Error; err != nil {
+		return err
+	}
+	return recordStatusChange(tx, survey.SurveyID, "", NormalizeStatus(survey.Status), userID)
 }
 
 func GetSurveyByID(surveyID string) (*common.Survey, error) {
@@ -50,78 +110,679 @@ type SurveyResponse struct {
 	ResponseCount  int       `json:"responseCount"`
 	OwnerID        string    `json:"ownerId"`
 	OwnerName      string    `json:"ownerName"`
+	FolderID       string    `json:"folderId"`
+	Tags           []string  `json:"tags"`
 	CreateTime     time.Time `json:"createTime"`
 	LastUpdateTime time.Time `json:"lastUpdateTime"`
 }
 
-func ListSurveys(userID string, count, skip int) ([]SurveyResponse, int64, int64, error) {
-	var surveys []common.Survey
-	var total int64
+// SurveyListQuery 问卷列表的搜索、筛选与排序参数
+type SurveyListQuery struct {
+	Keyword     string     // 标题/描述关键字
+	Statuses    []string   // 状态筛选
+	FolderID    *string    // 文件夹筛选，空字符串表示根目录
+	Tag         string     // 标签筛选
+	CreatedFrom *time.Time // 创建时间下限（含）
+	CreatedTo   *time.Time // 创建时间上限（不含）
+	UpdatedFrom *time.Time // 更新时间下限（含）
+	UpdatedTo   *time.Time // 更新时间上限（不含）
+	SortBy      string     // 排序字段：createTime / lastUpdateTime / responseCount
+	Ascending   bool       // 是否升序
+	Cursor      string     // 上一页返回的游标
+	Count       int        // 每页数量
+	Skip        int        // 未使用游标时的偏移量
+}
+
+// sortColumns 排序字段到数据库列的映射
+var sortColumns = map[string]string{
+	"createTime":     "CreateTime",
+	"lastUpdateTime": "LastUpdateTime",
+	"responseCount":  "ResponseCount",
+}
+
+// surveyListRow 问卷列表查询结果
+type surveyListRow struct {
+	SurveyID       string    `gorm:"column:SurveyID"`
+	AccessID       string    `gorm:"column:AccessID"`
+	Title          string    `gorm:"column:Title"`
+	Status         string    `gorm:"column:Status"`
+	ResponseCount  int       `gorm:"column:ResponseCount"`
+	UserID         string    `gorm:"column:UserID"`
+	OwnerName      string    `gorm:"column:OwnerName"`
+	FolderID       string    `gorm:"column:FolderID"`
+	CreateTime     time.Time `gorm:"column:CreateTime"`
+	LastUpdateTime time.Time `gorm:"column:LastUpdateTime"`
+}
 
-	// 统计用户的问卷总数
-	if err := common.DB.Model(&common.Survey{}).Where("UserID = ?", userID).Count(&total).Error; err != nil {
-		return nil, 0, 0, err
+// statusAliases 返回数据库中与指定生命周期状态等价的所有状态值
+func statusAliases(status string) []string {
+	target := NormalizeStatus(status)
+	aliases := []string{target}
+	for legacy, mapped := range legacyStatuses {
+		if mapped == target {
+			aliases = append(aliases, legacy)
+		}
+	}
+	if target == StatusDraft {
+		aliases = append(aliases, "")
 	}
+	return aliases
+}
 
-	// 分页查询用户的问卷
-	if err := common.DB.Model(&common.Survey{}).Where("UserID = ?", userID).Offset(skip).Limit(count).Find(&surveys).Error; err != nil {
-		return nil, 0, 0, err
+// encodeListCursor 根据最后一条记录生成游标
+func encodeListCursor(sortBy string, row surveyListRow) string {
+	var value string
+	switch sortBy {
+	case "responseCount":
+		value = strconv.Itoa(row.ResponseCount)
+	case "lastUpdateTime":
+		value = row.LastUpdateTime.Format(time.RFC3339Nano)
+	default:
+		value = row.CreateTime.Format(time.RFC3339Nano)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value + "|" + row.SurveyID))
+}
+
+// decodeListCursor 解析游标，返回排序值与问卷 ID
+func decodeListCursor(sortBy, cursor string) (interface{}, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", errors.New("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, "", errors.New("invalid cursor")
+	}
+	if sortBy == "responseCount" {
+		value, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, "", errors.New("invalid cursor")
+		}
+		return value, parts[1], nil
+	}
+	value, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, "", errors.New("invalid cursor")
+	}
+	return value, parts[1], nil
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符，配合 ESCAPE '\\' 使用
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike 转义关键字中的 %、_ 和 \，使其按字面匹配
+func escapeLike(keyword string) string {
+	return likeEscaper.Replace(keyword)
+}
+
+// ListSurveys 按条件搜索用户的问卷，返回当前页、总数、当前页数量和下一页游标
+func ListSurveys(userID string, query SurveyListQuery) ([]SurveyResponse, int64, int64, string, error) {
+	if query.SortBy == "" {
+		query.SortBy = "createTime"
+	}
+	column, ok := sortColumns[query.SortBy]
+	if !ok {
+		return nil, 0, 0, "", errors.New("invalid sort field")
+	}
+	if query.Count <= 0 || query.Count > 100 {
+		query.Count = 10
+	}
+
+	// 构建筛选条件
+	db := common.DB.Model(&common.Survey{}).Where("surveys.UserID = ?", userID)
+	if query.Keyword != "" {
+		keyword := "%" + escapeLike(query.Keyword) + "%"
+		db = db.Where(`(surveys.Title LIKE ? ESCAPE '\\' OR surveys.Description LIKE ? ESCAPE '\\')`, keyword, keyword)
+	}
+	if len(query.Statuses) > 0 {
+		statuses := []string{}
+		for _, status := range query.Statuses {
+			statuses = append(statuses, statusAliases(status)...)
+		}
+		db = db.Where("surveys.Status IN ?", statuses)
+	}
+	if query.FolderID != nil {
+		db = db.Where("surveys.FolderID = ?", *query.FolderID)
+	}
+	if query.Tag != "" {
+		db = db.Where("surveys.SurveyID IN (?)", common.DB.Model(&common.SurveyTag{}).Select("SurveyID").Where("UserID = ? AND Tag = ?", userID, query.Tag))
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("surveys.CreateTime >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		db = db.Where("surveys.CreateTime < ?", *query.CreatedTo)
+	}
+	if query.UpdatedFrom != nil {
+		db = db.Where("surveys.LastUpdateTime >= ?", *query.UpdatedFrom)
+	}
+	if query.UpdatedTo != nil {
+		db = db.Where("surveys.LastUpdateTime < ?", *query.UpdatedTo)
 	}
-	// 调试日志
-	// fmt.Printf("Surveys retrieved: %+v\n", surveys)
-	// 构建响应结构
-	var responses []SurveyResponse
-	for _, survey := range surveys {
-		var owner common.User
 
-		// 获取问卷拥有者信息
-		if err := common.DB.Where("UserID = ?", survey.UserID).First(&owner).Error; err != nil {
-			return nil, 0, 0, err
+	// 统计符合条件的问卷总数
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, 0, "", err
+	}
+
+	// 按排序字段和问卷 ID 做稳定排序，游标分页
+	direction, comparator := "DESC", "<"
+	if query.Ascending {
+		direction, comparator = "ASC", ">"
+	}
+	page := db.Session(&gorm.Session{})
+	if query.Cursor != "" {
+		value, lastID, err := decodeListCursor(query.SortBy, query.Cursor)
+		if err != nil {
+			return nil, 0, 0, "", err
 		}
+		page = page.Where(
+			fmt.Sprintf("(surveys.%[1]s %[2]s ? OR (surveys.%[1]s = ? AND surveys.SurveyID %[2]s ?))", column, comparator),
+			value, value, lastID,
+		)
+	} else if query.Skip > 0 {
+		page = page.Offset(query.Skip)
+	}
+
+	// 关联查询问卷拥有者，多取一条用于判断是否还有下一页
+	var rows []surveyListRow
+	if err := page.
+		Select("surveys.SurveyID, surveys.AccessID, surveys.Title, surveys.Status, surveys.ResponseCount, surveys.UserID, surveys.FolderID, surveys.CreateTime, surveys.LastUpdateTime, users.UserName AS OwnerName").
+		Joins("LEFT JOIN users ON users.UserID = surveys.UserID").
+		Order(fmt.Sprintf("surveys.%s %s, surveys.SurveyID %s", column, direction, direction)).
+		Limit(query.Count + 1).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, 0, "", err
+	}
 
-		// 构建单个问卷响应
+	nextCursor := ""
+	if len(rows) > query.Count {
+		rows = rows[:query.Count]
+		nextCursor = encodeListCursor(query.SortBy, rows[len(rows)-1])
+	}
+
+	// 批量获取当前页问卷的标签
+	surveyIDs := []string{}
+	for _, row := range rows {
+		surveyIDs = append(surveyIDs, row.SurveyID)
+	}
+	tags, err := getSurveyTags(surveyIDs)
+	if err != nil {
+		return nil, 0, 0, "", err
+	}
+
+	// 构建响应结构
+	responses := []SurveyResponse{}
+	for _, row := range rows {
+		surveyTags := tags[row.SurveyID]
+		if surveyTags == nil {
+			surveyTags = []string{}
+		}
 		responses = append(responses, SurveyResponse{
-			SurveyID:       survey.SurveyID,
-			AccessID:       survey.AccessID,
-			Title:          survey.Title,
-			Status:         survey.Status,
-			ResponseCount:  survey.ResponseCount,
-			OwnerID:        survey.UserID,
-			OwnerName:      owner.UserName,
-			CreateTime:     survey.CreateTime,
-			LastUpdateTime: survey.LastUpdateTime,
+			SurveyID:       row.SurveyID,
+			AccessID:       row.AccessID,
+			Title:          row.Title,
+			Status:         NormalizeStatus(row.Status),
+			ResponseCount:  row.ResponseCount,
+			OwnerID:        row.UserID,
+			OwnerName:      row.OwnerName,
+			FolderID:       row.FolderID,
+			Tags:           surveyTags,
+			CreateTime:     row.CreateTime,
+			LastUpdateTime: row.LastUpdateTime,
 		})
 	}
 
-	return responses, total, int64(len(responses)), nil
+	return responses, total, int64(len(responses)), nextCursor, nil
 }
 
-// UpdateSurveyStatus 更新问卷状态
-func UpdateSurveyStatus(surveyID, status string) error {
-	// 确保状态合法性
-	validStatuses := map[string]bool{
-		"Ongoing": true, "Suspended": true, "Deleted": true,
+// 问卷生命周期状态
+const (
+	StatusDraft     = "Draft"     // 草稿
+	StatusPublished = "Published" // 已发布，接受答卷
+	StatusPaused    = "Paused"    // 已暂停
+	StatusClosed    = "Closed"    // 已结束
+	StatusArchived  = "Archived"  // 已归档
+)
+
+// statusTransitions 允许的状态迁移
+var statusTransitions = map[string][]string{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusPaused, StatusClosed},
+	StatusPaused:    {StatusPublished, StatusClosed},
+	StatusClosed:    {StatusPublished, StatusArchived},
+	StatusArchived:  {StatusClosed},
+}
+
+// legacyStatuses 旧版本状态到生命周期状态的映射
+var legacyStatuses = map[string]string{
+	"Ongoing":   StatusPublished,
+	"open":      StatusPublished,
+	"Suspended": StatusPaused,
+	"Deleted":   StatusArchived,
+}
+
+// NormalizeStatus 将旧版本状态转换为生命周期状态，空状态视为草稿
+func NormalizeStatus(status string) string {
+	if mapped, ok := legacyStatuses[status]; ok {
+		return mapped
+	}
+	if status == "" {
+		return StatusDraft
 	}
-	if !validStatuses[status] {
+	return status
+}
+
+// IsSurveyOpen 判断问卷当前是否接受答卷
+func IsSurveyOpen(survey *common.Survey) bool {
+	return NormalizeStatus(survey.Status) == StatusPublished
+}
+
+// canTransition 判断状态迁移是否合法
+func canTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// recordStatusChange 记录一次状态变更
+func recordStatusChange(tx *gorm.DB, surveyID, fromStatus, toStatus, userID string) error {
+	history := common.SurveyStatusHistory{
+		SurveyID:   surveyID,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+		UserID:     userID,
+		ChangedAt:  time.Now(),
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return errors.New("failed to record status history")
+	}
+	return nil
+}
+
+// UpdateSurveyStatus 按生命周期更新问卷状态，"Deleted" 会将问卷移入回收站
+func UpdateSurveyStatus(surveyID, status, userID string) error {
+	if status == "Deleted" {
+		return TrashSurvey(surveyID, userID)
+	}
+
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return err
+	}
+
+	// 确保状态合法性
+	target := NormalizeStatus(status)
+	if _, ok := statusTransitions[target]; !ok {
 		return errors.New("invalid status")
 	}
+	current := NormalizeStatus(survey.Status)
+	if current == target {
+		return nil
+	}
+	if !canTransition(current, target) {
+		return fmt.Errorf("cannot change status from %s to %s", current, target)
+	}
 
-	// 更新状态
-	return common.DB.Model(&common.Survey{}).Where("SurveyID = ?", surveyID).Update("status", status).Error
+	// 更新状态并记录历史
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&common.Survey{}).
+			Where("SurveyID = ? AND Status = ?", surveyID, survey.Status).
+			Update("Status", target)
+		if result.Error != nil {
+			return errors.New("failed to update status")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("survey status changed concurrently, please retry")
+		}
+		if err := touchSurvey(tx, survey, userID, -1); err != nil {
+			return err
+		}
+		return recordStatusChange(tx, surveyID, current, target, userID)
+	})
 }
 
-// CopySurvey 复制问卷
-func CopySurvey(surveyID string) error {
+// StatusHistoryResponse 状态变更记录
+type StatusHistoryResponse struct {
+	FromStatus string    `json:"fromStatus"`
+	ToStatus   string    `json:"toStatus"`
+	UserID     string    `json:"userId"`
+	UserName   string    `json:"userName"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
+
+// GetSurveyStatusHistory 获取问卷的状态变更历史
+func GetSurveyStatusHistory(surveyID, userID string) ([]StatusHistoryResponse, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
+	}
+
+	histories := []StatusHistoryResponse{}
+	err := common.DB.Table("survey_status_histories AS h").
+		Select("h.FromStatus AS from_status, h.ToStatus AS to_status, h.UserID AS user_id, u.UserName AS user_name, h.ChangedAt AS changed_at").
+		Joins("LEFT JOIN users AS u ON u.UserID = h.UserID").
+		Where("h.SurveyID = ?", surveyID).
+		Order("h.ChangedAt ASC, h.ID ASC").
+		Scan(&histories).Error
+	if err != nil {
+		return nil, errors.New("failed to retrieve status history")
+	}
+	return histories, nil
+}
+
+// CopySurvey 深拷贝问卷，包括问题、选项和填空，返回新问卷 ID
+func CopySurvey(surveyID, userID string) (string, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	newSurveyID := uuid.New().String()
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		accessID, err := generateAccessID(tx)
+		if err != nil {
+			return err
+		}
+
+		// 复制正在编辑的题目及其选项、填空，包括未发布的草稿
+		model, questionIDs, mapping, err := copyEditingQuestions(tx, survey, newSurveyID)
+		if err != nil {
+			return err
+		}
+
+		// 创建新的问卷，重置访问 ID 和答卷统计
+		now := time.Now()
+		newSurvey := *survey
+		newSurvey.SurveyID = newSurveyID
+		newSurvey.AccessID = accessID
+		newSurvey.Title = survey.Title + " (copy)"
+		newSurvey.Status = StatusDraft
+		newSurvey.CreateTime = now
+		newSurvey.LastUpdateTime = now
+		newSurvey.LastUpdateUser = userID
+		newSurvey.Revision = 0
+		newSurvey.CurrentVersion = 0
+		newSurvey.PublishedVersion = 0
+		newSurvey.DraftContent = "" // 草稿内容已作为新问卷的题目保存
+		newSurvey.ResponseCount = 0
+		newSurvey.ResponseIDs = nil
+		newSurvey.QuestionIDs = questionIDs
+		newSurvey.RandomizeQuestions = model.RandomizeQuestions
+		newSurvey.QuizMode = model.QuizMode
+		newSurvey.ShowScore = model.ShowScore
+		newSurvey.Variables = encodeVariables(model.Variables)
+		if err := tx.Create(&newSurvey).Error; err != nil {
+			return errors.New("failed to create survey copy")
+		}
+
+		// 复制译文，字段标识使用新的题目和选项 ID
+		if err := copyTranslations(tx, survey.SurveyID, newSurveyID, mapping); err != nil {
+			return err
+		}
+
+		// 复制标签
+		var surveyTags []common.SurveyTag
+		if err := tx.Where("SurveyID = ?", survey.SurveyID).Find(&surveyTags).Error; err != nil {
+			return errors.New("failed to copy survey tags")
+		}
+		for _, surveyTag := range surveyTags {
+			surveyTag.SurveyID = newSurveyID
+			surveyTag.UserID = userID
+			if err := tx.Create(&surveyTag).Error; err != nil {
+				return errors.New("failed to copy survey tags")
+			}
+		}
+		return recordStatusChange(tx, newSurveyID, "", StatusDraft, userID)
+	})
+	if err != nil {
+		return "", err
+	}
+	return newSurveyID, nil
+}
+
+// copyEditingQuestions 将问卷正在编辑的题目（有草稿时为草稿，否则为已发布的题目）复制到目标问卷下，
+// 为每个问题、选项和填空生成新 ID，返回题目所在的问卷模型、新的 QuestionIDs 列表和旧 ID 到新 ID 的映射
+func copyEditingQuestions(tx *gorm.DB, survey *common.Survey, dstSurveyID string) (*SurveyModel, string, map[string]string, error) {
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	mapping := assignFreshIDs(model.Questions)
+	questionIDs, err := createQuestions(tx, dstSurveyID, model.Questions)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return model, questionIDs, mapping, nil
+}
+
+// accessIDAlphabet 短链接访问 ID 字符集，去除了易混淆的字符
+const accessIDAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// accessIDLength 自动生成的访问 ID 长度
+const accessIDLength = 8
+
+// accessIDPattern 自定义访问 ID 格式
+var accessIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,64}$`)
+
+// generateAccessID 生成未被占用的短链接访问 ID，冲突时重试
+func generateAccessID(tx *gorm.DB) (string, error) {
+	for i := 0; i < 10; i++ {
+		buf := make([]byte, accessIDLength)
+		if _, err := crand.Read(buf); err != nil {
+			return "", err
+		}
+		for j := range buf {
+			buf[j] = accessIDAlphabet[int(buf[j])%len(accessIDAlphabet)]
+		}
+		accessID := string(buf)
+
+		taken, err := accessIDTaken(tx, accessID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return accessID, nil
+		}
+	}
+	return "", errors.New("failed to generate access ID")
+}
+
+// RepairAccessIDs 为重复或为空的访问 ID 重新生成，须在为 AccessID 建立唯一索引前执行。
+// 重复的访问 ID 保留给最早创建的问卷，其余问卷的短链接会改变
+func RepairAccessIDs() error {
+	if !common.DB.Migrator().HasTable(&common.Survey{}) {
+		return nil
+	}
+
+	var duplicated []string
+	err := common.DB.Unscoped().Model(&common.Survey{}).
+		Where("AccessID <> ''").
+		Group("AccessID").
+		Having("COUNT(*) > 1").
+		Pluck("AccessID", &duplicated).Error
+	if err != nil {
+		return err
+	}
+
+	var surveys []common.Survey
+	err = common.DB.Unscoped().Select("SurveyID", "AccessID").
+		Where("AccessID IS NULL OR AccessID = '' OR AccessID IN ?", append(duplicated, "")).
+		Order("CreateTime ASC").
+		Find(&surveys).Error
+	if err != nil {
+		return err
+	}
+
+	kept := map[string]bool{}
+	for _, survey := range surveys {
+		if survey.AccessID != "" && !kept[survey.AccessID] {
+			kept[survey.AccessID] = true
+			continue
+		}
+		accessID, err := generateAccessID(common.DB)
+		if err != nil {
+			return err
+		}
+		err = common.DB.Unscoped().Model(&common.Survey{}).
+			Where("SurveyID = ?", survey.SurveyID).
+			Update("AccessID", accessID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accessIDTaken 检查访问 ID 是否已被使用
+func accessIDTaken(tx *gorm.DB, accessID string) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&common.Survey{}).Where("AccessID = ?", accessID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// getOwnedSurvey 获取问卷并校验其属于指定用户
+func getOwnedSurvey(surveyID, userID string) (*common.Survey, error) {
 	var survey common.Survey
 	if err := common.DB.Where("SurveyID = ?", surveyID).First(&survey).Error; err != nil {
-		return errors.New("survey not found")
+		return nil, errors.New("survey not found")
+	}
+	if survey.UserID != userID {
+		return nil, errors.New("permission denied")
+	}
+	return &survey, nil
+}
+
+// ResolveAccessResponse 访问 ID 解析结果
+type ResolveAccessResponse struct {
+	SurveyID string `json:"surveyId"`
+	AccessID string `json:"accessId"`
+	Title    string `json:"title"`
+}
+
+// ResolveAccessID 根据访问 ID 查找问卷
+func ResolveAccessID(accessID string) (*ResolveAccessResponse, error) {
+	var survey common.Survey
+	if err := common.DB.Where("AccessID = ?", accessID).First(&survey).Error; err != nil {
+		return nil, errors.New("survey not found")
+	}
+	return &ResolveAccessResponse{
+		SurveyID: survey.SurveyID,
+		AccessID: survey.AccessID,
+		Title:    survey.Title,
+	}, nil
+}
+
+// RegenerateAccessID 重新生成问卷的访问 ID，使旧链接失效
+func RegenerateAccessID(surveyID, userID string) (string, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	accessID, err := generateAccessID(common.DB)
+	if err != nil {
+		return "", err
+	}
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(survey).Update("AccessID", accessID).Error; err != nil {
+			return errors.New("failed to update access ID")
+		}
+		return touchSurvey(tx, survey, userID, -1)
+	})
+	if err != nil {
+		return "", err
+	}
+	return accessID, nil
+}
+
+// SetCustomAccessID 为问卷设置自定义访问 ID
+func SetCustomAccessID(surveyID, userID, accessID string) error {
+	if !accessIDPattern.MatchString(accessID) {
+		return errors.New("access ID must be 3-64 letters, numbers, underscores or hyphens")
 	}
 
-	// 创建新的问卷
-	newSurvey := survey
-	newSurvey.SurveyID = uuid.New().String() // 让 GORM 自动生成新 ID
-	if err := common.DB.Create(&newSurvey).Error; err != nil {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
 		return err
 	}
+	if survey.AccessID == accessID {
+		return nil
+	}
+
+	taken, err := accessIDTaken(common.DB, accessID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.New("access ID already in use")
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(survey).Update("AccessID", accessID).Error; err != nil {
+			return errors.New("failed to update access ID")
+		}
+		return touchSurvey(tx, survey, userID, -1)
+	})
+}
+
+// ErrRevisionConflict 保存时问卷已被其他编辑者修改
+var ErrRevisionConflict = errors.New("survey has been modified by another editor")
+
+// touchSurvey 记录一次问卷修改：递增修订号并更新最后修改时间与修改人。
+// expectedRevision 不小于 0 时，仅当当前修订号与之相同才会更新，否则返回 ErrRevisionConflict
+func touchSurvey(tx *gorm.DB, survey *common.Survey, userID string, expectedRevision int) error {
+	now := time.Now()
+	query := tx.Model(&common.Survey{}).Where("SurveyID = ?", survey.SurveyID)
+	if expectedRevision >= 0 {
+		query = query.Where("Revision = ?", expectedRevision)
+	}
+	result := query.Updates(map[string]interface{}{
+		"Revision":       gorm.Expr("Revision + 1"),
+		"LastUpdateTime": now,
+		"LastUpdateUser": userID,
+	})
+	if result.Error != nil {
+		return errors.New("failed to update survey revision")
+	}
+	if result.RowsAffected == 0 {
+		return ErrRevisionConflict
+	}
+
+	// 读取更新后的修订号
+	var revision int
+	if err := tx.Model(&common.Survey{}).Where("SurveyID = ?", survey.SurveyID).Pluck("Revision", &revision).Error; err != nil {
+		return errors.New("failed to read survey revision")
+	}
+	survey.Revision = revision
+	survey.LastUpdateTime = now
+	survey.LastUpdateUser = userID
 	return nil
 }
+
+// GetSurveyRevision 获取问卷当前修订号
+func GetSurveyRevision(surveyID string) (int, error) {
+	var survey common.Survey
+	if err := common.DB.Select("Revision").Where("SurveyID = ?", surveyID).First(&survey).Error; err != nil {
+		return 0, errors.New("survey not found")
+	}
+	return survey.Revision, nil
+}
+
+// splitIDs 将逗号分隔的 ID 列表拆分为数组，忽略空值和默认占位 "{}"
+func splitIDs(ids string) []string {
+	result := []string{}
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || id == "{}" {
+			continue
+		}
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/common"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 问卷导出文档格式
+const (
+	SurveyDocumentFormat  = "survey-form-platform/survey"
+	SurveyDocumentVersion = 1
+)
+
+// SurveyDocument 可移植的问卷定义文档
+type SurveyDocument struct {
+	Format        string                 `json:"format"`
+	FormatVersion int                    `json:"formatVersion"`
+	ExportedAt    time.Time              `json:"exportedAt"`
+	Survey        SurveyDocumentMeta     `json:"survey"`
+	Appearance    SurveyAppearance       `json:"appearance"`
+	Settings      SurveyDocumentSettings `json:"settings"`
+	Questions     []QuestionModel        `json:"questions"`
+	Variables     []VariableModel        `json:"variables,omitempty"`
+
+	Languages *SurveyDocumentLanguages `json:"languages,omitempty"` // 多语言设置和译文
+}
+
+// SurveyDocumentMeta 问卷基本信息
+type SurveyDocumentMeta struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// SurveyDocumentSettings 问卷答题设置，不包含访问密码
+type SurveyDocumentSettings struct {
+	SurveyBehavior
+
+	RandomizeQuestions bool `json:"randomizeQuestions"`
+	QuizMode           bool `json:"quizMode"`
+	ShowScore          bool `json:"showScore"`
+}
+
+// SurveyDocumentLanguages 问卷的语言设置和译文，译文按语言和字段标识组织，字段标识使用文档中的题目和选项 ID
+type SurveyDocumentLanguages struct {
+	TranslationSettings
+
+	Translations map[string]map[string]string `json:"translations,omitempty"`
+}
+
+// SurveyDocumentError 导入文档校验失败，包含所有错误
+type SurveyDocumentError struct {
+	Errors []string
+}
+
+func (e *SurveyDocumentError) Error() string {
+	return "invalid survey document: " + strings.Join(e.Errors, "; ")
+}
+
+// ExportSurveyService 将问卷导出为文档，存在草稿时导出草稿
+func ExportSurveyService(surveyID, userID string) (*SurveyDocument, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SurveyDocument{
+		Format:        SurveyDocumentFormat,
+		FormatVersion: SurveyDocumentVersion,
+		ExportedAt:    time.Now(),
+		Survey: SurveyDocumentMeta{
+			Title:       model.Title,
+			Description: survey.Description,
+		},
+		Appearance: appearanceOf(survey),
+		Settings: SurveyDocumentSettings{
+			SurveyBehavior: behaviorOf(survey),
+
+			RandomizeQuestions: model.RandomizeQuestions,
+			QuizMode:           model.QuizMode,
+			ShowScore:          model.ShowScore,
+		},
+		Questions: model.Questions,
+		Variables: model.Variables,
+		Languages: exportDocumentLanguages(survey),
+	}, nil
+}
+
+// exportDocumentLanguages 导出问卷的语言设置和全部译文，未设置语言时返回 nil
+func exportDocumentLanguages(survey *common.Survey) *SurveyDocumentLanguages {
+	if survey.DefaultLanguage == "" {
+		return nil
+	}
+	languages := &SurveyDocumentLanguages{
+		TranslationSettings: TranslationSettings{
+			DefaultLanguage: survey.DefaultLanguage,
+			Languages:       splitLanguages(survey.Languages),
+		},
+		Translations: map[string]map[string]string{},
+	}
+	var rows []common.SurveyTranslation
+	common.DB.Where("SurveyID = ?", survey.SurveyID).Find(&rows)
+	for _, row := range rows {
+		if languages.Translations[row.Language] == nil {
+			languages.Translations[row.Language] = map[string]string{}
+		}
+		languages.Translations[row.Language][row.Key] = row.Text
+	}
+	return languages
+}
+
+// ParseSurveyDocument 解析并校验问卷文档
+func ParseSurveyDocument(data []byte) (*SurveyDocument, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var doc SurveyDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, &SurveyDocumentError{Errors: []string{"malformed document: " + err.Error()}}
+	}
+	if errs := validateSurveyDocument(&doc); len(errs) > 0 {
+		return nil, &SurveyDocumentError{Errors: errs}
+	}
+	return &doc, nil
+}
+
+// validateSurveyDocument 按文档格式校验内容，返回所有错误
+func validateSurveyDocument(doc *SurveyDocument) []string {
+	errs := []string{}
+	if doc.Format != SurveyDocumentFormat {
+		errs = append(errs, fmt.Sprintf("format: must be %q", SurveyDocumentFormat))
+	}
+	if doc.FormatVersion < 1 || doc.FormatVersion > SurveyDocumentVersion {
+		errs = append(errs, fmt.Sprintf("formatVersion: unsupported version %d", doc.FormatVersion))
+	}
+	if strings.TrimSpace(doc.Survey.Title) == "" {
+		errs = append(errs, "survey.title: is required")
+	}
+	errs = append(errs, validateAppearance("appearance", doc.Appearance)...)
+	errs = append(errs, validateBehavior("settings", doc.Settings.SurveyBehavior)...)
+	errs = append(errs, validateQuestionModels("questions", doc.Questions)...)
+	errs = append(errs, validateQuestionRules("questions", doc.Questions)...)
+	errs = append(errs, validateDocumentLanguages(doc)...)
+	return append(errs, validateSurveyLogic(doc.Questions, doc.Variables)...)
+}
+
+// validateDocumentLanguages 校验语言设置，译文只能属于已设置的其他语言，字段须为文档中存在的可翻译字段
+func validateDocumentLanguages(doc *SurveyDocument) []string {
+	if doc.Languages == nil {
+		return nil
+	}
+	settings, err := normalizeTranslationSettings(doc.Languages.TranslationSettings)
+	if err != nil {
+		return []string{"languages: " + err.Error()}
+	}
+
+	survey := &common.Survey{}
+	known := map[string]bool{}
+	for _, entry := range translatableTexts(survey, &SurveyModel{Questions: doc.Questions}) {
+		known[entry.Key] = true
+	}
+	enabled := map[string]bool{}
+	for _, language := range settings.Languages {
+		enabled[language] = true
+	}
+
+	errs := []string{}
+	for language, texts := range doc.Languages.Translations {
+		if !enabled[language] {
+			errs = append(errs, fmt.Sprintf("languages.translations: language %q is not enabled", language))
+			continue
+		}
+		for key := range texts {
+			if !known[key] {
+				errs = append(errs, fmt.Sprintf("languages.translations.%s: unknown key %q", language, key))
+			}
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+// validateQuestionModels 校验题目结构，path 为错误信息中的字段路径前缀
+func validateQuestionModels(path string, questions []QuestionModel) []string {
+	errs := []string{}
+	seen := map[string]bool{}
+	checkID := func(field, id string) {
+		if id == "" {
+			return
+		}
+		if seen[id] {
+			errs = append(errs, fmt.Sprintf("%s: duplicate id %q", field, id))
+		}
+		seen[id] = true
+	}
+
+	for i, question := range questions {
+		prefix := fmt.Sprintf("%s[%d]", path, i)
+		checkID(prefix+".QuestionID", question.QuestionID)
+		if _, ok := questionTypeLabels[question.Type]; !ok {
+			errs = append(errs, fmt.Sprintf("%s.QuestionType: unsupported type %q", prefix, question.Type))
+		}
+		if strings.TrimSpace(question.Title) == "" {
+			errs = append(errs, prefix+".Title: is required")
+		}
+
+		switch question.Type {
+		case "SingleChoice", "MultiChoice":
+			if len(question.Options) == 0 {
+				errs = append(errs, prefix+".Options: at least one option is required")
+			}
+			if question.LeastChoice < 0 || question.MaxChoice < 0 {
+				errs = append(errs, prefix+".LeastChoice: choice limits must not be negative")
+			} else if question.MaxChoice > 0 && question.LeastChoice > question.MaxChoice {
+				errs = append(errs, prefix+".LeastChoice: must not exceed MaxChoice")
+			}
+			for j, option := range question.Options {
+				checkID(fmt.Sprintf("%s.Options[%d].OptionID", prefix, j), option.OptionID)
+				if strings.TrimSpace(option.OptionContent) == "" {
+					errs = append(errs, fmt.Sprintf("%s.Options[%d].OptionContent: is required", prefix, j))
+				}
+			}
+		case "SingleTextFillIn", "MultiTextFillIn":
+			if len(question.TextFillIns) == 0 {
+				errs = append(errs, prefix+".TextFillIns: at least one fill-in is required")
+			}
+			for j, textFillIn := range question.TextFillIns {
+				checkID(fmt.Sprintf("%s.TextFillIns[%d].TextFillInID", prefix, j), textFillIn.TextFillInID)
+			}
+		case "SingleNumFillIn", "MultiNumFillIn":
+			if len(question.NumFillIns) == 0 {
+				errs = append(errs, prefix+".NumFillIns: at least one fill-in is required")
+			}
+			for j, numFillIn := range question.NumFillIns {
+				checkID(fmt.Sprintf("%s.NumFillIns[%d].NumFillInID", prefix, j), numFillIn.NumFillInID)
+			}
+		}
+	}
+	return errs
+}
+
+// assignFreshIDs 为题目、选项和填空生成新 ID，返回旧 ID 到新 ID 的映射
+func assignFreshIDs(questions []QuestionModel) map[string]string {
+	mapping := map[string]string{}
+	renew := func(id string) string {
+		newID := uuid.New().String()
+		if id != "" {
+			mapping[id] = newID
+		}
+		return newID
+	}
+
+	for i := range questions {
+		question := &questions[i]
+		question.QuestionID = renew(question.QuestionID)
+		question.Label = questionTypeLabels[question.Type]
+		for j := range question.Options {
+			question.Options[j].OptionID = renew(question.Options[j].OptionID)
+		}
+		for j := range question.TextFillIns {
+			question.TextFillIns[j].TextFillInID = renew(question.TextFillIns[j].TextFillInID)
+		}
+		for j := range question.NumFillIns {
+			question.NumFillIns[j].NumFillInID = renew(question.NumFillIns[j].NumFillInID)
+		}
+	}
+	return mapping
+}
+
+// ImportSurveyService 根据文档在用户名下创建新问卷，返回新问卷 ID
+func ImportSurveyService(userID string, doc *SurveyDocument) (string, error) {
+	survey := NewDefaultSurvey(strings.TrimSpace(doc.Survey.Title))
+	survey.Description = doc.Survey.Description
+
+	// 外观和答题设置，未指定的时间保留默认值
+	applyAppearance(&survey, doc.Appearance)
+	applyBehavior(&survey, doc.Settings.SurveyBehavior)
+	survey.RandomizeQuestions = doc.Settings.RandomizeQuestions
+	survey.QuizMode = doc.Settings.QuizMode
+	survey.ShowScore = doc.Settings.ShowScore
+	survey.Variables = encodeVariables(doc.Variables)
+	if doc.Languages != nil {
+		settings, _ := normalizeTranslationSettings(doc.Languages.TranslationSettings)
+		survey.DefaultLanguage = settings.DefaultLanguage
+		survey.Languages = strings.Join(settings.Languages, ",")
+	}
+
+	// 题库归属于用户，导入的题目不保留题库关联
+	questions := doc.Questions
+	mapping := assignFreshIDs(questions)
+	for i := range questions {
+		questions[i].BankQuestionID = ""
+	}
+
+	err := common.DB.Transaction(func(tx *gorm.DB) error {
+		questionIDs, err := createQuestions(tx, survey.SurveyID, questions)
+		if err != nil {
+			return err
+		}
+		survey.QuestionIDs = questionIDs
+		if err := insertSurvey(tx, userID, &survey); err != nil {
+			return err
+		}
+		return importDocumentTranslations(tx, survey.SurveyID, doc.Languages, mapping)
+	})
+	if err != nil {
+		return "", err
+	}
+	return survey.SurveyID, nil
+}
+
+// importDocumentTranslations 保存文档中的译文，字段标识按 mapping 换成新的题目和选项 ID
+func importDocumentTranslations(tx *gorm.DB, surveyID string, languages *SurveyDocumentLanguages, mapping map[string]string) error {
+	if languages == nil {
+		return nil
+	}
+	for language, texts := range languages.Translations {
+		for key, text := range texts {
+			newKey, ok := remapTranslationKey(key, mapping)
+			if !ok || strings.TrimSpace(text) == "" {
+				continue
+			}
+			translation := common.SurveyTranslation{
+				SurveyID: surveyID,
+				Language: language,
+				Key:      newKey,
+				Text:     text,
+			}
+			if err := tx.Create(&translation).Error; err != nil {
+				return errors.New("failed to import translations")
+			}
+		}
+	}
+	return nil
+}
+
+// AsSurveyDocumentError 判断错误是否为文档校验错误
+func AsSurveyDocumentError(err error) (*SurveyDocumentError, bool) {
+	var docErr *SurveyDocumentError
+	ok := errors.As(err, &docErr)
+	return docErr, ok
+}
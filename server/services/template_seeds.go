@@ -0,0 +1,133 @@
+package services
+
+import (
+	"server/common"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// questionTypeLabels 题目类型对应的中文名称
+var questionTypeLabels = map[string]string{
+	"SingleChoice":     "单选题",
+	"MultiChoice":      "多选题",
+	"SingleTextFillIn": "单项填空题",
+	"MultiTextFillIn":  "多项填空题",
+	"SingleNumFillIn":  "数字填空题",
+	"MultiNumFillIn":   "多项数字填空题",
+}
+
+// templateSeed 内置模板定义
+type templateSeed struct {
+	TemplateID  string
+	Name        string
+	Description string
+	Category    string
+	Questions   []QuestionModel
+}
+
+// seedChoice 构造选择题，multi 为 true 时为多选题
+func seedChoice(multi bool, title string, options ...string) QuestionModel {
+	questionType, least, max := "SingleChoice", 1, 1
+	if multi {
+		questionType, max = "MultiChoice", len(options)
+	}
+	question := QuestionModel{
+		Type:        questionType,
+		Label:       questionTypeLabels[questionType],
+		QuestionID:  uuid.New().String(),
+		Title:       title,
+		LeastChoice: least,
+		MaxChoice:   max,
+	}
+	for _, content := range options {
+		question.Options = append(question.Options, common.QuestionOption{
+			OptionID:      uuid.New().String(),
+			OptionContent: content,
+		})
+	}
+	return question
+}
+
+// seedScale 构造 min 到 max 的打分单选题
+func seedScale(title string, min, max int) QuestionModel {
+	options := []string{}
+	for i := min; i <= max; i++ {
+		options = append(options, strconv.Itoa(i))
+	}
+	return seedChoice(false, title, options...)
+}
+
+// seedText 构造单项文本填空题
+func seedText(title string) QuestionModel {
+	return QuestionModel{
+		Type:       "SingleTextFillIn",
+		Label:      questionTypeLabels["SingleTextFillIn"],
+		QuestionID: uuid.New().String(),
+		Title:      title,
+		TextFillIns: []common.QuestionTextFillIn{
+			{TextFillInID: uuid.New().String()},
+		},
+	}
+}
+
+// builtinTemplates 内置模板列表
+func builtinTemplates() []templateSeed {
+	satisfaction := []string{"非常满意", "满意", "一般", "不满意", "非常不满意"}
+
+	return []templateSeed{
+		{
+			TemplateID:  "builtin-customer-satisfaction",
+			Name:        "客户满意度调查",
+			Description: "了解客户对产品与服务的整体满意度及改进建议",
+			Category:    "feedback",
+			Questions: []QuestionModel{
+				seedChoice(false, "您对我们产品的整体满意度如何？", satisfaction...),
+				seedChoice(false, "您对我们客户服务的满意度如何？", satisfaction...),
+				seedChoice(false, "您使用我们的产品多久了？", "不到一个月", "1-6 个月", "6-12 个月", "一年以上"),
+				seedChoice(true, "您最看重产品的哪些方面？", "质量", "价格", "易用性", "售后服务", "品牌"),
+				seedText("您对我们有什么改进建议？"),
+			},
+		},
+		{
+			TemplateID:  "builtin-nps",
+			Name:        "净推荐值（NPS）调查",
+			Description: "衡量客户忠诚度的标准 NPS 问卷",
+			Category:    "marketing",
+			Questions: []QuestionModel{
+				seedScale("您有多大可能向朋友或同事推荐我们？（0 表示完全不可能，10 表示非常可能）", 0, 10),
+				seedText("您给出这个分数的主要原因是什么？"),
+				seedText("我们可以做些什么让您更愿意推荐我们？"),
+			},
+		},
+		{
+			TemplateID:  "builtin-event-feedback",
+			Name:        "活动反馈调查",
+			Description: "收集参与者对活动组织、内容与场地的反馈",
+			Category:    "event",
+			Questions: []QuestionModel{
+				seedChoice(false, "您对本次活动的整体评价如何？", satisfaction...),
+				seedChoice(true, "您是通过什么渠道了解到本次活动的？", "社交媒体", "邮件", "朋友推荐", "官网", "其他"),
+				seedScale("您对活动内容的评分（1-5 分）", 1, 5),
+				seedScale("您对活动场地与组织的评分（1-5 分）", 1, 5),
+				seedChoice(false, "您是否愿意参加我们今后的活动？", "愿意", "不确定", "不愿意"),
+				seedText("您对今后的活动有什么建议？"),
+			},
+		},
+		{
+			TemplateID:  "builtin-course-evaluation",
+			Name:        "课程评价调查",
+			Description: "学生对课程内容、授课方式与收获的评价",
+			Category:    "education",
+			Questions: []QuestionModel{
+				seedScale("课程内容的清晰程度（1-5 分）", 1, 5),
+				seedScale("授课教师的讲解水平（1-5 分）", 1, 5),
+				seedChoice(false, "课程难度如何？", "太简单", "适中", "太难"),
+				seedChoice(false, "每周在本课程上投入的学习时间", "少于 2 小时", "2-5 小时", "5-10 小时", "10 小时以上"),
+				seedChoice(false, "您是否会向其他同学推荐本课程？", "会", "不确定", "不会"),
+				seedText("您认为本课程最大的收获是什么？"),
+				seedText("您对课程有什么改进建议？"),
+			},
+		},
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"server/common"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TemplateResponse 模板信息
+type TemplateResponse struct {
+	TemplateID    string    `json:"templateId"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Category      string    `json:"category"`
+	QuestionCount int       `json:"questionCount"`
+	Shared        bool      `json:"shared"`
+	BuiltIn       bool      `json:"builtIn"`
+	OwnerID       string    `json:"ownerId"`
+	CreateTime    time.Time `json:"createTime"`
+}
+
+// toTemplateResponse 构造模板响应
+func toTemplateResponse(template common.SurveyTemplate) TemplateResponse {
+	return TemplateResponse{
+		TemplateID:    template.TemplateID,
+		Name:          template.Name,
+		Description:   template.Description,
+		Category:      template.Category,
+		QuestionCount: template.QuestionCount,
+		Shared:        template.Shared,
+		BuiltIn:       template.BuiltIn,
+		OwnerID:       template.UserID,
+		CreateTime:    template.CreateTime,
+	}
+}
+
+// getVisibleTemplate 获取用户可见的模板：内置、全站共享或本人创建
+func getVisibleTemplate(templateID, userID string) (*common.SurveyTemplate, error) {
+	var template common.SurveyTemplate
+	if err := common.DB.Where("TemplateID = ?", templateID).First(&template).Error; err != nil {
+		return nil, errors.New("template not found")
+	}
+	if !template.BuiltIn && !template.Shared && template.UserID != userID {
+		return nil, errors.New("template not found")
+	}
+	return &template, nil
+}
+
+// ListTemplates 获取用户可见的模板，可按分类筛选
+func ListTemplates(userID, category string) ([]TemplateResponse, error) {
+	db := common.DB.Where("(BuiltIn = ? OR Shared = ? OR UserID = ?)", true, true, userID)
+	if category != "" {
+		db = db.Where("Category = ?", category)
+	}
+
+	var templates []common.SurveyTemplate
+	if err := db.Order("BuiltIn DESC, CreateTime DESC").Find(&templates).Error; err != nil {
+		return nil, errors.New("failed to retrieve templates")
+	}
+
+	responses := []TemplateResponse{}
+	for _, template := range templates {
+		responses = append(responses, toTemplateResponse(template))
+	}
+	return responses, nil
+}
+
+// SaveSurveyAsTemplate 将问卷保存为模板
+func SaveSurveyAsTemplate(userID, surveyID, name, description, category string, shared bool) (*TemplateResponse, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = survey.Title
+	}
+	if description == "" {
+		description = survey.Description
+	}
+
+	template := common.SurveyTemplate{
+		TemplateID:  uuid.New().String(),
+		UserID:      userID,
+		Name:        name,
+		Description: description,
+		Category:    strings.TrimSpace(category),
+		Shared:      shared,
+		CreateTime:  time.Now(),
+	}
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		// 保存正在编辑的题目，包括未发布的草稿
+		model, questionIDs, mapping, err := copyEditingQuestions(tx, survey, template.TemplateID)
+		if err != nil {
+			return err
+		}
+		template.QuestionIDs = questionIDs
+		template.RandomizeQuestions = model.RandomizeQuestions
+		template.QuizMode = model.QuizMode
+		template.ShowScore = model.ShowScore
+		template.Variables = encodeVariables(model.Variables)
+		template.QuestionCount = len(splitIDs(questionIDs))
+		template.DefaultLanguage = survey.DefaultLanguage
+		template.Languages = survey.Languages
+		if err := tx.Create(&template).Error; err != nil {
+			return errors.New("failed to save template")
+		}
+		return copyTranslations(tx, survey.SurveyID, template.TemplateID, mapping)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toTemplateResponse(template)
+	return &response, nil
+}
+
+// CreateSurveyFromTemplate 基于模板创建新问卷，返回新问卷 ID
+func CreateSurveyFromTemplate(userID, templateID, title string) (string, error) {
+	template, err := getVisibleTemplate(templateID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = template.Name
+	}
+	survey := NewDefaultSurvey(title)
+
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		questions, err := loadQuestionModels(tx, template.TemplateID, template.QuestionIDs)
+		if err != nil {
+			return err
+		}
+		mapping := assignFreshIDs(questions)
+		questionIDs, err := createQuestions(tx, survey.SurveyID, questions)
+		if err != nil {
+			return err
+		}
+		survey.QuestionIDs = questionIDs
+		survey.RandomizeQuestions = template.RandomizeQuestions
+		survey.QuizMode = template.QuizMode
+		survey.ShowScore = template.ShowScore
+		survey.Variables = template.Variables
+		survey.DefaultLanguage = template.DefaultLanguage
+		survey.Languages = template.Languages
+		if err := insertSurvey(tx, userID, &survey); err != nil {
+			return err
+		}
+		return copyTranslations(tx, template.TemplateID, survey.SurveyID, mapping)
+	})
+	if err != nil {
+		return "", err
+	}
+	return survey.SurveyID, nil
+}
+
+// DeleteTemplate 删除用户创建的模板
+func DeleteTemplate(userID, templateID string) error {
+	var template common.SurveyTemplate
+	if err := common.DB.Where("TemplateID = ?", templateID).First(&template).Error; err != nil {
+		return errors.New("template not found")
+	}
+	if template.BuiltIn || template.UserID != userID {
+		return errors.New("permission denied")
+	}
+
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteQuestions(tx, templateID); err != nil {
+			return err
+		}
+		if err := tx.Where("SurveyID = ?", templateID).Delete(&common.SurveyTranslation{}).Error; err != nil {
+			return errors.New("failed to delete template translations")
+		}
+		if err := tx.Delete(&template).Error; err != nil {
+			return errors.New("failed to delete template")
+		}
+		return nil
+	})
+}
+
+// SeedBuiltinTemplates 加载内置模板，已存在的模板不会重复创建
+func SeedBuiltinTemplates() error {
+	for _, seed := range builtinTemplates() {
+		var count int64
+		if err := common.DB.Model(&common.SurveyTemplate{}).Where("TemplateID = ?", seed.TemplateID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := common.DB.Transaction(func(tx *gorm.DB) error {
+			questionIDs, err := createQuestions(tx, seed.TemplateID, seed.Questions)
+			if err != nil {
+				return err
+			}
+			template := common.SurveyTemplate{
+				TemplateID:    seed.TemplateID,
+				Name:          seed.Name,
+				Description:   seed.Description,
+				Category:      seed.Category,
+				Shared:        true,
+				BuiltIn:       true,
+				QuestionCount: len(seed.Questions),
+				QuestionIDs:   questionIDs,
+				CreateTime:    time.Now(),
+			}
+			return tx.Create(&template).Error
+		})
+		if err != nil {
+			return errors.New("failed to seed template " + seed.TemplateID + ": " + err.Error())
+		}
+	}
+	return nil
+}
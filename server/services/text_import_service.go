@@ -0,0 +1,271 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"server/common"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 文本题目格式：
+//
+//	# 问卷标题（可选）
+//	1. 您的年龄段是？ *
+//	> 题目描述（可选）
+//	- 18 岁以下
+//	- 18-30 岁
+//	2. 您常用哪些功能？ [multi]
+//	- 搜索
+//	- 收藏
+//	3. 您的联系方式 [text:2]
+//	4. 您每周使用几次？ [number]
+//
+// 题目标记：[single]、[multi]、[text]、[number]，填空题可用 [text:N]、[number:N] 指定填空数；
+// 标题首尾的 "*" 表示必答。
+var (
+	textQuestionPattern = regexp.MustCompile(`^\d+\s*[.、)）]\s*(.*)$`)
+	textOptionPattern   = regexp.MustCompile(`^[-*+•]\s+(.*)$`)
+	textMarkerPattern   = regexp.MustCompile(`\[([a-zA-Z]+)(?::(\d+))?\]`)
+)
+
+// textFillInLimit 单个填空题允许的最大填空数
+const textFillInLimit = 20
+
+// TextParseError 文本解析错误，Line 从 1 开始
+type TextParseError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// parsedTextQuestion 解析中的题目及其所在行
+type parsedTextQuestion struct {
+	line      int
+	kind      string // single / multi / text / number
+	fillIns   int
+	question  QuestionModel
+	hasMarker bool
+}
+
+// ParseSurveyText 将文本格式的题目解析为 SurveyModel，返回解析结果和所有错误
+func ParseSurveyText(text string) (*SurveyModel, []TextParseError) {
+	model := &SurveyModel{Questions: []QuestionModel{}}
+	errs := []TextParseError{}
+	parsed := []*parsedTextQuestion{}
+
+	var current *parsedTextQuestion
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// 问卷标题
+		if strings.HasPrefix(line, "#") {
+			if current != nil || model.Title != "" {
+				errs = append(errs, TextParseError{lineNumber, "survey title must appear once before the first question"})
+				continue
+			}
+			model.Title = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+
+		// 题目
+		if match := textQuestionPattern.FindStringSubmatch(line); match != nil {
+			question, err := parseTextQuestionLine(lineNumber, match[1])
+			if err != nil {
+				errs = append(errs, *err)
+			}
+			current = question
+			parsed = append(parsed, current)
+			continue
+		}
+
+		// 题目描述
+		if strings.HasPrefix(line, ">") {
+			if current == nil {
+				errs = append(errs, TextParseError{lineNumber, "description must follow a question"})
+				continue
+			}
+			description := strings.TrimSpace(strings.TrimPrefix(line, ">"))
+			if current.question.Description != "" {
+				current.question.Description += "\n"
+			}
+			current.question.Description += description
+			continue
+		}
+
+		// 选项
+		if match := textOptionPattern.FindStringSubmatch(line); match != nil {
+			if current == nil {
+				errs = append(errs, TextParseError{lineNumber, "option must follow a question"})
+				continue
+			}
+			content := strings.TrimSpace(match[1])
+			if content == "" {
+				errs = append(errs, TextParseError{lineNumber, "option content is empty"})
+				continue
+			}
+			current.question.Options = append(current.question.Options, common.QuestionOption{
+				OptionID:      uuid.New().String(),
+				OptionContent: content,
+			})
+			continue
+		}
+
+		errs = append(errs, TextParseError{lineNumber, fmt.Sprintf("unrecognized line %q", line)})
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, TextParseError{lineNumber + 1, "failed to read text: " + err.Error()})
+	}
+
+	if len(parsed) == 0 {
+		errs = append(errs, TextParseError{lineNumber, "no questions found"})
+	}
+	for _, item := range parsed {
+		if err := finishTextQuestion(item); err != nil {
+			errs = append(errs, *err)
+		}
+		model.Questions = append(model.Questions, item.question)
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	return model, errs
+}
+
+// parseTextQuestionLine 解析题目行中的标题、类型标记和必答标记
+func parseTextQuestionLine(lineNumber int, content string) (*parsedTextQuestion, *TextParseError) {
+	item := &parsedTextQuestion{
+		line: lineNumber,
+		kind: "single",
+		question: QuestionModel{
+			QuestionID: uuid.New().String(),
+		},
+	}
+
+	var parseErr *TextParseError
+	for _, marker := range textMarkerPattern.FindAllStringSubmatch(content, -1) {
+		kind := strings.ToLower(marker[1])
+		switch kind {
+		case "single", "multi", "text", "number":
+		default:
+			parseErr = &TextParseError{lineNumber, fmt.Sprintf("unknown marker [%s]", marker[1])}
+			continue
+		}
+		if item.hasMarker && item.kind != kind {
+			parseErr = &TextParseError{lineNumber, "conflicting question type markers"}
+			continue
+		}
+		item.kind, item.hasMarker = kind, true
+
+		if marker[2] != "" {
+			if kind != "text" && kind != "number" {
+				parseErr = &TextParseError{lineNumber, fmt.Sprintf("marker [%s] does not take a count", marker[1])}
+				continue
+			}
+			count, _ := strconv.Atoi(marker[2])
+			if count < 1 || count > textFillInLimit {
+				parseErr = &TextParseError{lineNumber, fmt.Sprintf("fill-in count must be between 1 and %d", textFillInLimit)}
+				continue
+			}
+			item.fillIns = count
+		}
+	}
+	content = strings.TrimSpace(textMarkerPattern.ReplaceAllString(content, ""))
+
+	// 标题首尾的 * 表示必答
+	if strings.HasSuffix(content, "*") {
+		item.question.Required = true
+		content = strings.TrimSpace(strings.TrimSuffix(content, "*"))
+	}
+	if strings.HasPrefix(content, "*") {
+		item.question.Required = true
+		content = strings.TrimSpace(strings.TrimPrefix(content, "*"))
+	}
+
+	item.question.Title = content
+	if content == "" && parseErr == nil {
+		parseErr = &TextParseError{lineNumber, "question title is empty"}
+	}
+	return item, parseErr
+}
+
+// finishTextQuestion 根据类型标记确定题型并补全填空和选择数
+func finishTextQuestion(item *parsedTextQuestion) *TextParseError {
+	question := &item.question
+	switch item.kind {
+	case "single", "multi":
+		question.Type = "SingleChoice"
+		question.LeastChoice, question.MaxChoice = 1, 1
+		if item.kind == "multi" {
+			question.Type = "MultiChoice"
+			question.MaxChoice = len(question.Options)
+		}
+		if !question.Required {
+			question.LeastChoice = 0
+		}
+		question.Label = questionTypeLabels[question.Type]
+		if len(question.Options) == 0 {
+			return &TextParseError{item.line, "choice question needs at least one option"}
+		}
+	case "text", "number":
+		count := item.fillIns
+		if count == 0 {
+			count = 1
+		}
+		if item.kind == "text" {
+			question.Type = "SingleTextFillIn"
+			if count > 1 {
+				question.Type = "MultiTextFillIn"
+			}
+			for i := 0; i < count; i++ {
+				question.TextFillIns = append(question.TextFillIns, common.QuestionTextFillIn{TextFillInID: uuid.New().String()})
+			}
+		} else {
+			question.Type = "SingleNumFillIn"
+			if count > 1 {
+				question.Type = "MultiNumFillIn"
+			}
+			for i := 0; i < count; i++ {
+				question.NumFillIns = append(question.NumFillIns, common.QuestionNumFillIn{NumFillInID: uuid.New().String()})
+			}
+		}
+		question.Label = questionTypeLabels[question.Type]
+		if len(question.Options) > 0 {
+			return &TextParseError{item.line, "fill-in question cannot have options"}
+		}
+	}
+	return nil
+}
+
+// ImportSurveyTextService 将解析后的题目写入问卷草稿，replace 为 false 时追加到现有题目之后，
+// 为 true 时只替换题目，题目随机、测验和计算变量等问卷级设置保持不变，变量引用失效时保存失败。
+// 返回保存后的修订号
+func ImportSurveyTextService(surveyId, userID string, expectedRevision int, parsed *SurveyModel, replace bool) (int, error) {
+	survey, err := getOwnedSurvey(surveyId, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return 0, err
+	}
+	if replace {
+		model.Questions = parsed.Questions
+	} else {
+		model.Questions = append(model.Questions, parsed.Questions...)
+	}
+	if parsed.Title != "" {
+		model.Title = parsed.Title
+	}
+
+	return SaveSurveyEditService(surveyId, userID, expectedRevision, model)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSurveyText(t *testing.T) {
+	text := strings.Join([]string{
+		"# 用户调查",
+		"1. 您的年龄段是？ *",
+		"> 请选择一项",
+		"> 按周岁计算",
+		"- 18 岁以下",
+		"- 18-30 岁",
+		"",
+		"2、您常用哪些功能？ [multi]",
+		"* 搜索",
+		"+ 收藏",
+		"- 分享",
+		"3) 您的联系方式 [text:2]",
+		"4） * 您每周使用几次？ [number]",
+	}, "\n")
+
+	model, errs := ParseSurveyText(text)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if model.Title != "用户调查" {
+		t.Errorf("Title = %q, want %q", model.Title, "用户调查")
+	}
+
+	tests := []struct {
+		title       string
+		typ         string
+		required    bool
+		description string
+		options     int
+		textFillIns int
+		numFillIns  int
+		leastChoice int
+		maxChoice   int
+	}{
+		{"您的年龄段是？", "SingleChoice", true, "请选择一项\n按周岁计算", 2, 0, 0, 1, 1},
+		{"您常用哪些功能？", "MultiChoice", false, "", 3, 0, 0, 0, 3},
+		{"您的联系方式", "MultiTextFillIn", false, "", 0, 2, 0, 0, 0},
+		{"您每周使用几次？", "SingleNumFillIn", true, "", 0, 0, 1, 0, 0},
+	}
+	if len(model.Questions) != len(tests) {
+		t.Fatalf("got %d questions, want %d", len(model.Questions), len(tests))
+	}
+	for i, tt := range tests {
+		question := model.Questions[i]
+		if question.Title != tt.title || question.Type != tt.typ || question.Required != tt.required || question.Description != tt.description {
+			t.Errorf("question %d = {%q %s required=%v %q}, want {%q %s required=%v %q}", i+1,
+				question.Title, question.Type, question.Required, question.Description,
+				tt.title, tt.typ, tt.required, tt.description)
+		}
+		if len(question.Options) != tt.options || len(question.TextFillIns) != tt.textFillIns || len(question.NumFillIns) != tt.numFillIns {
+			t.Errorf("question %d has %d options, %d text and %d number fill-ins, want %d, %d and %d", i+1,
+				len(question.Options), len(question.TextFillIns), len(question.NumFillIns),
+				tt.options, tt.textFillIns, tt.numFillIns)
+		}
+		if question.LeastChoice != tt.leastChoice || question.MaxChoice != tt.maxChoice {
+			t.Errorf("question %d choice = %d..%d, want %d..%d", i+1,
+				question.LeastChoice, question.MaxChoice, tt.leastChoice, tt.maxChoice)
+		}
+		if question.QuestionID == "" || question.Label == "" {
+			t.Errorf("question %d is missing its ID or label", i+1)
+		}
+	}
+}
+
+func TestParseSurveyTextErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		line    int
+		message string
+	}{
+		{"empty", "", 0, "no questions found"},
+		{"option first", "- 选项", 1, "option must follow a question"},
+		{"description first", "> 描述", 1, "description must follow a question"},
+		{"late title", "1. 题目 [text]\n# 标题", 2, "survey title must appear once"},
+		{"unknown line", "1. 题目 [text]\n随便写写", 2, "unrecognized line"},
+		{"empty title", "1. [text]", 1, "question title is empty"},
+		{"unknown marker", "1. 题目 [date]", 1, "unknown marker [date]"},
+		{"conflicting markers", "1. 题目 [text] [number]", 1, "conflicting question type markers"},
+		{"count on choice", "1. 题目 [multi:2]\n- A", 1, "does not take a count"},
+		{"count too large", "1. 题目 [text:21]", 1, "fill-in count must be between 1 and 20"},
+		{"choice without options", "1. 题目\n2. 另一题 [text]", 1, "choice question needs at least one option"},
+		{"fill-in with options", "1. 题目 [number]\n- A", 1, "fill-in question cannot have options"},
+		{"bare dash", "1. 题目\n- A\n- ", 3, "unrecognized line"},
+	}
+	for _, tt := range tests {
+		_, errs := ParseSurveyText(tt.text)
+		found := false
+		for _, err := range errs {
+			if err.Line == tt.line && strings.Contains(err.Message, tt.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: errors = %v, want line %d %q", tt.name, errs, tt.line, tt.message)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"server/common"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 译文导出文档格式
+const TranslationDocumentFormat = "survey-form-platform/translation"
+
+// maxSurveyLanguages 问卷最多支持的其他语言数量
+const maxSurveyLanguages = 20
+
+// languagePattern 语言标识，如 zh、en、zh-CN
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,2}$`)
+
+// TranslationSettings 问卷的默认语言和其他语言
+type TranslationSettings struct {
+	DefaultLanguage string   `json:"defaultLanguage"`
+	Languages       []string `json:"languages"`
+}
+
+// TranslationEntry 单个可翻译字段，Source 为默认语言的原文
+type TranslationEntry struct {
+	Key    string `json:"key"`
+	Source string `json:"source,omitempty"`
+	Text   string `json:"text"`
+}
+
+// TranslationDocument 交给译者的译文文档
+type TranslationDocument struct {
+	Format         string             `json:"format"`
+	SurveyID       string             `json:"surveyId"`
+	SourceLanguage string             `json:"sourceLanguage"`
+	Language       string             `json:"language"`
+	Strings        []TranslationEntry `json:"strings"`
+}
+
+// SurveyTexts 返回给答题者的问卷级文本，已按答题语言翻译
+type SurveyTexts struct {
+	Description string `json:"description"`
+	FailMessage string `json:"failMessage"`
+	ShowContent string `json:"showContent"`
+	ButtonText  string `json:"buttonText"`
+}
+
+// splitLanguages 解析以逗号分隔的语言列表
+func splitLanguages(languages string) []string {
+	result := []string{}
+	for _, language := range strings.Split(languages, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			result = append(result, language)
+		}
+	}
+	return result
+}
+
+// hasLanguage 判断问卷是否设置了指定的其他语言
+func hasLanguage(survey *common.Survey, language string) bool {
+	for _, item := range splitLanguages(survey.Languages) {
+		if strings.EqualFold(item, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLanguage 在问卷语言中查找与请求语言匹配的语言，先精确匹配，再按主语言匹配（zh-TW 匹配 zh）
+func matchLanguage(survey *common.Survey, requested string) string {
+	available := append([]string{survey.DefaultLanguage}, splitLanguages(survey.Languages)...)
+	for _, language := range available {
+		if language != "" && strings.EqualFold(language, requested) {
+			return language
+		}
+	}
+	primary := strings.SplitN(requested, "-", 2)[0]
+	for _, language := range available {
+		if language != "" && strings.EqualFold(strings.SplitN(language, "-", 2)[0], primary) {
+			return language
+		}
+	}
+	return ""
+}
+
+// parseAcceptLanguage 按权重从高到低返回 Accept-Language 中的语言
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		language string
+		q        float64
+	}
+	items := []weighted{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		language := strings.TrimSpace(fields[0])
+		if language == "" || language == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, weighted{language, q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	languages := []string{}
+	for _, item := range items {
+		languages = append(languages, item.language)
+	}
+	return languages
+}
+
+// ResolveLanguage 确定答题语言：优先使用请求参数指定的语言，其次按 Accept-Language，
+// 都不支持时使用问卷的默认语言
+func ResolveLanguage(survey *common.Survey, requested, acceptLanguage string) string {
+	if requested != "" {
+		if language := matchLanguage(survey, requested); language != "" {
+			return language
+		}
+	}
+	for _, candidate := range parseAcceptLanguage(acceptLanguage) {
+		if language := matchLanguage(survey, candidate); language != "" {
+			return language
+		}
+	}
+	return survey.DefaultLanguage
+}
+
+// translatableTexts 列出问卷中所有可翻译字段及其原文，题目按编辑内容的顺序
+func translatableTexts(survey *common.Survey, model *SurveyModel) []TranslationEntry {
+	entries := []TranslationEntry{
+		{Key: "survey.title", Source: model.Title},
+		{Key: "survey.description", Source: survey.Description},
+		{Key: "survey.failMessage", Source: survey.FailMessage},
+		{Key: "survey.showContent", Source: survey.ShowContent},
+	}
+	buttonText := ""
+	if survey.ButtonText != nil {
+		buttonText = *survey.ButtonText
+	}
+	entries = append(entries, TranslationEntry{Key: "survey.buttonText", Source: buttonText})
+
+	for _, question := range model.Questions {
+		entries = append(entries,
+			TranslationEntry{Key: "question." + question.QuestionID + ".title", Source: question.Title},
+			TranslationEntry{Key: "question." + question.QuestionID + ".description", Source: question.Description},
+		)
+		for _, option := range question.Options {
+			entries = append(entries, TranslationEntry{Key: "option." + option.OptionID + ".content", Source: option.OptionContent})
+		}
+	}
+	return entries
+}
+
+// loadTranslations 读取问卷在指定语言下的译文，默认语言没有译文
+func loadTranslations(survey *common.Survey, language string) map[string]string {
+	translations := map[string]string{}
+	if language == "" || strings.EqualFold(language, survey.DefaultLanguage) {
+		return translations
+	}
+	var rows []common.SurveyTranslation
+	common.DB.Where("SurveyID = ? AND Language = ?", survey.SurveyID, language).Find(&rows)
+	for _, row := range rows {
+		translations[row.Key] = row.Text
+	}
+	return translations
+}
+
+// remapTranslationKey 将字段标识中的题目或选项 ID 换成 mapping 中的新 ID，
+// 问卷级字段保持不变，ID 不在 mapping 中时返回 false
+func remapTranslationKey(key string, mapping map[string]string) (string, bool) {
+	for _, prefix := range []string{"question.", "option."} {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := key[len(prefix):]
+		dot := strings.LastIndex(rest, ".")
+		if dot < 0 {
+			return "", false
+		}
+		newID, ok := mapping[rest[:dot]]
+		if !ok {
+			return "", false
+		}
+		return prefix + newID + rest[dot:], true
+	}
+	return key, strings.HasPrefix(key, "survey.")
+}
+
+// copyTranslations 将 srcID 的译文复制到 dstID 下，题目和选项的字段标识按 mapping 换成新 ID
+func copyTranslations(tx *gorm.DB, srcID, dstID string, mapping map[string]string) error {
+	var rows []common.SurveyTranslation
+	if err := tx.Where("SurveyID = ?", srcID).Find(&rows).Error; err != nil {
+		return errors.New("failed to copy translations")
+	}
+	for _, row := range rows {
+		key, ok := remapTranslationKey(row.Key, mapping)
+		if !ok {
+			continue
+		}
+		row.SurveyID = dstID
+		row.Key = key
+		if err := tx.Create(&row).Error; err != nil {
+			return errors.New("failed to copy translations")
+		}
+	}
+	return nil
+}
+
+// translateSurveyModel 将问卷和题目文本替换为指定语言的译文，没有译文的字段保留原文
+func translateSurveyModel(survey *common.Survey, model *SurveyModel, language string) {
+	translations := loadTranslations(survey, language)
+	translate := func(key, source string) string {
+		if text, ok := translations[key]; ok && text != "" {
+			return text
+		}
+		return source
+	}
+
+	buttonText := ""
+	if survey.ButtonText != nil {
+		buttonText = *survey.ButtonText
+	}
+	model.Language = language
+	model.Languages = nil
+	if survey.DefaultLanguage != "" {
+		model.Languages = append([]string{survey.DefaultLanguage}, splitLanguages(survey.Languages)...)
+	}
+	model.Title = translate("survey.title", model.Title)
+	model.Texts = &SurveyTexts{
+		Description: translate("survey.description", survey.Description),
+		FailMessage: translate("survey.failMessage", survey.FailMessage),
+		ShowContent: translate("survey.showContent", survey.ShowContent),
+		ButtonText:  translate("survey.buttonText", buttonText),
+	}
+	if len(translations) == 0 {
+		return
+	}
+	for i := range model.Questions {
+		question := &model.Questions[i]
+		question.Title = translate("question."+question.QuestionID+".title", question.Title)
+		question.Description = translate("question."+question.QuestionID+".description", question.Description)
+		for j := range question.Options {
+			option := &question.Options[j]
+			option.OptionContent = translate("option."+option.OptionID+".content", option.OptionContent)
+		}
+	}
+}
+
+// GetTranslationSettings 获取问卷的语言设置
+func GetTranslationSettings(surveyID, userID string) (*TranslationSettings, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TranslationSettings{
+		DefaultLanguage: survey.DefaultLanguage,
+		Languages:       splitLanguages(survey.Languages),
+	}, nil
+}
+
+// normalizeTranslationSettings 校验语言设置并去除语言标识两端的空白
+func normalizeTranslationSettings(settings TranslationSettings) (TranslationSettings, error) {
+	settings.DefaultLanguage = strings.TrimSpace(settings.DefaultLanguage)
+	if !languagePattern.MatchString(settings.DefaultLanguage) {
+		return settings, errors.New("invalid default language")
+	}
+	if len(settings.Languages) > maxSurveyLanguages {
+		return settings, fmt.Errorf("at most %d languages are allowed", maxSurveyLanguages)
+	}
+	languages := []string{}
+	seen := map[string]bool{strings.ToLower(settings.DefaultLanguage): true}
+	for _, language := range settings.Languages {
+		language = strings.TrimSpace(language)
+		if !languagePattern.MatchString(language) {
+			return settings, fmt.Errorf("invalid language %q", language)
+		}
+		if seen[strings.ToLower(language)] {
+			return settings, fmt.Errorf("duplicate language %q", language)
+		}
+		seen[strings.ToLower(language)] = true
+		languages = append(languages, language)
+	}
+	settings.Languages = languages
+	return settings, nil
+}
+
+// UpdateTranslationSettings 修改问卷的语言设置，移除的语言的译文一并删除
+func UpdateTranslationSettings(surveyID, userID string, settings TranslationSettings) (*TranslationSettings, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err = normalizeTranslationSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	languages := settings.Languages
+
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(survey).Updates(map[string]interface{}{
+			"DefaultLanguage": settings.DefaultLanguage,
+			"Languages":       strings.Join(languages, ","),
+		}).Error
+		if err != nil {
+			return errors.New("failed to update languages")
+		}
+
+		query := tx.Where("SurveyID = ?", surveyID)
+		if len(languages) > 0 {
+			query = query.Where("Language NOT IN ?", languages)
+		}
+		if err := query.Delete(&common.SurveyTranslation{}).Error; err != nil {
+			return errors.New("failed to delete translations")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TranslationSettings{DefaultLanguage: settings.DefaultLanguage, Languages: languages}, nil
+}
+
+// ExportTranslations 导出指定语言的译文文档，包含所有可翻译字段的原文和已有译文
+func ExportTranslations(surveyID, userID, language string) (*TranslationDocument, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLanguage(survey, language) {
+		return nil, errors.New("language is not enabled for this survey")
+	}
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return nil, err
+	}
+
+	translations := loadTranslations(survey, language)
+	entries := []TranslationEntry{}
+	for _, entry := range translatableTexts(survey, model) {
+		if entry.Source == "" {
+			continue
+		}
+		entry.Text = translations[entry.Key]
+		entries = append(entries, entry)
+	}
+	return &TranslationDocument{
+		Format:         TranslationDocumentFormat,
+		SurveyID:       surveyID,
+		SourceLanguage: survey.DefaultLanguage,
+		Language:       language,
+		Strings:        entries,
+	}, nil
+}
+
+// ImportTranslations 导入译者返回的译文，译文为空时删除该字段的译文，返回更新的字段数。
+// 存在未知字段时不做任何修改
+func ImportTranslations(surveyID, userID string, doc TranslationDocument) (int, []string, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if doc.Format != "" && doc.Format != TranslationDocumentFormat {
+		return 0, nil, fmt.Errorf("format must be %q", TranslationDocumentFormat)
+	}
+	if !hasLanguage(survey, doc.Language) {
+		return 0, nil, errors.New("language is not enabled for this survey")
+	}
+	model, err := loadEditingModel(survey)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	known := map[string]bool{}
+	for _, entry := range translatableTexts(survey, model) {
+		known[entry.Key] = true
+	}
+	errs := []string{}
+	seen := map[string]bool{}
+	for i, entry := range doc.Strings {
+		if !known[entry.Key] {
+			errs = append(errs, fmt.Sprintf("strings[%d].key: unknown key %q", i, entry.Key))
+		} else if seen[entry.Key] {
+			errs = append(errs, fmt.Sprintf("strings[%d].key: duplicate key %q", i, entry.Key))
+		}
+		seen[entry.Key] = true
+	}
+	if len(errs) > 0 {
+		return 0, errs, errors.New("invalid translation document")
+	}
+
+	// 语言标识使用问卷设置中的写法
+	language := matchLanguage(survey, doc.Language)
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range doc.Strings {
+			err := tx.Where("SurveyID = ? AND Language = ? AND TranslationKey = ?", surveyID, language, entry.Key).
+				Delete(&common.SurveyTranslation{}).Error
+			if err != nil {
+				return errors.New("failed to update translations")
+			}
+			if strings.TrimSpace(entry.Text) == "" {
+				continue
+			}
+			translation := common.SurveyTranslation{
+				SurveyID: surveyID,
+				Language: language,
+				Key:      entry.Key,
+				Text:     entry.Text,
+			}
+			if err := tx.Create(&translation).Error; err != nil {
+				return errors.New("failed to update translations")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(doc.Strings), nil, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"server/common"
+	"server/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var trashRetention time.Duration
+var purgeInterval time.Duration
+
+// InitTrashConfig 初始化回收站配置
+func InitTrashConfig() {
+	trashRetention = parseDurationOrDefault(config.Config.Survey.TrashRetention, 30*24*time.Hour)
+	purgeInterval = parseDurationOrDefault(config.Config.Survey.PurgeInterval, time.Hour)
+}
+
+// parseDurationOrDefault 解析时长配置，为空时使用默认值
+func parseDurationOrDefault(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic("Invalid duration format in configuration: " + value + ": " + err.Error())
+	}
+	if duration <= 0 {
+		panic("Invalid duration in configuration: " + value + ": must be positive")
+	}
+	return duration
+}
+
+// TrashSurvey 将问卷移入回收站
+func TrashSurvey(surveyID, userID string) error {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return err
+	}
+	if err := common.DB.Delete(survey).Error; err != nil {
+		return errors.New("failed to move survey to trash")
+	}
+	return nil
+}
+
+// TrashSurveyResponse 回收站中的问卷
+type TrashSurveyResponse struct {
+	SurveyID      string    `json:"surveyId"`
+	Title         string    `json:"title"`
+	Status        string    `json:"status"`
+	ResponseCount int       `json:"responseCount"`
+	DeletedAt     time.Time `json:"deletedAt"`
+	PurgeAt       time.Time `json:"purgeAt"`
+}
+
+// ListTrash 获取用户回收站中的问卷
+func ListTrash(userID string) ([]TrashSurveyResponse, error) {
+	var surveys []common.Survey
+	if err := common.DB.Unscoped().
+		Where("UserID = ? AND DeletedAt IS NOT NULL", userID).
+		Order("DeletedAt DESC").
+		Find(&surveys).Error; err != nil {
+		return nil, errors.New("failed to retrieve trash")
+	}
+
+	responses := []TrashSurveyResponse{}
+	for _, survey := range surveys {
+		deletedAt := survey.DeletedAt.Time
+		responses = append(responses, TrashSurveyResponse{
+			SurveyID:      survey.SurveyID,
+			Title:         survey.Title,
+			Status:        NormalizeStatus(survey.Status),
+			ResponseCount: survey.ResponseCount,
+			DeletedAt:     deletedAt,
+			PurgeAt:       deletedAt.Add(trashRetention),
+		})
+	}
+	return responses, nil
+}
+
+// getTrashedSurvey 获取回收站中属于指定用户的问卷
+func getTrashedSurvey(surveyID, userID string) (*common.Survey, error) {
+	var survey common.Survey
+	if err := common.DB.Unscoped().
+		Where("SurveyID = ? AND DeletedAt IS NOT NULL", surveyID).
+		First(&survey).Error; err != nil {
+		return nil, errors.New("survey not found in trash")
+	}
+	if survey.UserID != userID {
+		return nil, errors.New("permission denied")
+	}
+	return &survey, nil
+}
+
+// RestoreSurvey 从回收站恢复问卷
+func RestoreSurvey(surveyID, userID string) error {
+	if _, err := getTrashedSurvey(surveyID, userID); err != nil {
+		return err
+	}
+	if err := common.DB.Unscoped().Model(&common.Survey{}).
+		Where("SurveyID = ?", surveyID).
+		Update("DeletedAt", nil).Error; err != nil {
+		return errors.New("failed to restore survey")
+	}
+	return nil
+}
+
+// PurgeSurvey 永久删除回收站中的问卷
+func PurgeSurvey(surveyID, userID string) error {
+	if _, err := getTrashedSurvey(surveyID, userID); err != nil {
+		return err
+	}
+	return common.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeSurveyData(tx, surveyID)
+	})
+}
+
+// purgeSurveyData 删除问卷及其所有关联数据，需在事务中调用
+func purgeSurveyData(tx *gorm.DB, surveyID string) error {
+	tables := []struct {
+		model interface{}
+		name  string
+	}{
+		{&common.ResponseOption{}, "response options"},
+		{&common.ResponseTextFillIn{}, "response text fill-ins"},
+		{&common.ResponseNumFillIn{}, "response num fill-ins"},
+		{&common.ResponseVariable{}, "response variables"},
+		{&common.QuestionResponse{}, "question responses"},
+		{&common.SurveyResponse{}, "survey responses"},
+		{&common.QuestionOption{}, "question options"},
+		{&common.QuestionTextFillIn{}, "text fill-ins"},
+		{&common.QuestionNumFillIn{}, "num fill-ins"},
+		{&common.Question{}, "questions"},
+		{&common.SurveyStatusHistory{}, "status history"},
+		{&common.SurveyTag{}, "tags"},
+		{&common.SurveyVersion{}, "versions"},
+		{&common.SurveyQuota{}, "quotas"},
+		{&common.SurveyTranslation{}, "translations"},
+	}
+	for _, table := range tables {
+		if err := tx.Where("SurveyID = ?", surveyID).Delete(table.model).Error; err != nil {
+			return errors.New("failed to delete " + table.name + " related to the survey")
+		}
+	}
+
+	if err := tx.Unscoped().Where("SurveyID = ?", surveyID).Delete(&common.Survey{}).Error; err != nil {
+		return errors.New("failed to delete survey")
+	}
+	return nil
+}
+
+// PurgeExpiredSurveys 永久删除超过保留期限的回收站问卷，返回删除数量
+func PurgeExpiredSurveys() (int, error) {
+	var surveyIDs []string
+	cutoff := time.Now().Add(-trashRetention)
+	if err := common.DB.Unscoped().Model(&common.Survey{}).
+		Where("DeletedAt IS NOT NULL AND DeletedAt < ?", cutoff).
+		Pluck("SurveyID", &surveyIDs).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, surveyID := range surveyIDs {
+		err := common.DB.Transaction(func(tx *gorm.DB) error {
+			return purgeSurveyData(tx, surveyID)
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge survey %s: %w", surveyID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartTrashPurgeJob 启动后台任务，定期清理回收站
+func StartTrashPurgeJob() {
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeExpiredSurveys()
+			if err != nil {
+				log.Printf("Trash purge failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired surveys from trash", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
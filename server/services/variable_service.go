@@ -0,0 +1,419 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"server/common"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// VariableModel 问卷计算变量，表达式语法见 expression_service.go
+type VariableModel struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// VariableStats 计算变量的统计结果
+type VariableStats struct {
+	Name       string  `json:"name"`
+	Expression string  `json:"expression"`
+	Count      int     `json:"count"` // 有计算结果的答卷数
+	Mean       float64 `json:"mean"`
+	Median     float64 `json:"median"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+}
+
+// VariableFilter 按计算变量筛选答卷的条件，如 anxiety>=10
+type VariableFilter struct {
+	Name     string
+	Operator string
+	Value    float64
+}
+
+// variableNamePattern 变量名只能包含字母、数字和下划线
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,49}$`)
+
+// variableFilterPattern 筛选条件格式：变量名 运算符 数字
+var variableFilterPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*(>=|<=|!=|==|=|>|<)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*$`)
+
+// filterOperators 筛选运算符到 SQL 运算符的映射
+var filterOperators = map[string]string{
+	"=": "=", "==": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">=",
+}
+
+// encodeVariables 将变量定义编码为 JSON 保存，没有变量时返回空
+func encodeVariables(variables []VariableModel) string {
+	if len(variables) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// decodeVariables 解析问卷保存的变量定义
+func decodeVariables(data string) []VariableModel {
+	variables := []VariableModel{}
+	if data != "" {
+		json.Unmarshal([]byte(data), &variables)
+	}
+	return variables
+}
+
+// isNumericQuestion 判断题目能否在表达式中取值
+func isNumericQuestion(question QuestionModel) bool {
+	switch question.Type {
+	case "SingleChoice", "MultiChoice", "SingleNumFillIn", "MultiNumFillIn":
+		return true
+	}
+	return false
+}
+
+// checkExpressionRefs 检查表达式引用的题目，limit 为可引用的题目数
+func checkExpressionRefs(field string, expr *expression, questions []QuestionModel, limit int) []string {
+	errs := []string{}
+	referenced := map[int]bool{}
+	for index := range expr.questions {
+		referenced[index] = true
+	}
+	for _, r := range expr.ranges {
+		// 区间超出题目数量时只检查终点，不逐题展开
+		if r.to >= len(questions) {
+			referenced[r.to] = true
+			continue
+		}
+		for index := r.from; index <= r.to; index++ {
+			referenced[index] = true
+		}
+	}
+
+	indexes := []int{}
+	for index := range referenced {
+		indexes = append(indexes, index)
+	}
+	for index := range expr.answered {
+		if !referenced[index] {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		switch {
+		case index >= limit && limit < len(questions):
+			errs = append(errs, fmt.Sprintf("%s: Q%d must reference an earlier question", field, index+1))
+		case index >= len(questions):
+			errs = append(errs, fmt.Sprintf("%s: Q%d does not exist", field, index+1))
+		case referenced[index] && !isNumericQuestion(questions[index]):
+			errs = append(errs, fmt.Sprintf("%s: Q%d is not a choice or number question", field, index+1))
+		}
+	}
+	return errs
+}
+
+// validateSurveyLogic 校验计算变量和题目显示条件。变量只能引用前面定义的变量；
+// 显示条件只能引用前面的题目，以及只依赖前面题目的变量
+func validateSurveyLogic(questions []QuestionModel, variables []VariableModel) []string {
+	errs := []string{}
+
+	// 每个变量依赖的最后一道题目的序号，-1 表示不依赖题目
+	lastQuestion := map[string]int{}
+	for i, variable := range variables {
+		field := fmt.Sprintf("variables[%d]", i)
+		if !variableNamePattern.MatchString(variable.Name) {
+			errs = append(errs, field+".name: must start with a letter and contain only letters, digits and underscores")
+			continue
+		}
+		if isReservedName(variable.Name) {
+			errs = append(errs, fmt.Sprintf("%s.name: %q is reserved", field, variable.Name))
+			continue
+		}
+		if _, ok := lastQuestion[variable.Name]; ok {
+			errs = append(errs, fmt.Sprintf("%s.name: duplicate variable %q", field, variable.Name))
+			continue
+		}
+
+		expr, err := parseExpression(variable.Expression)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s.expression: %v", field, err))
+			continue
+		}
+		errs = append(errs, checkExpressionRefs(field+".expression", expr, questions, len(questions))...)
+
+		last := -1
+		for index := range expr.questions {
+			last = max(last, index)
+		}
+		for _, r := range expr.ranges {
+			last = max(last, r.to)
+		}
+		for index := range expr.answered {
+			last = max(last, index)
+		}
+		for name := range expr.variables {
+			dependency, ok := lastQuestion[name]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s.expression: variable %s must be defined before it is used", field, name))
+				continue
+			}
+			last = max(last, dependency)
+		}
+		lastQuestion[variable.Name] = last
+	}
+
+	for i, question := range questions {
+		if strings.TrimSpace(question.DisplayCondition) == "" {
+			continue
+		}
+		field := fmt.Sprintf("questions[%d].DisplayCondition", i)
+		expr, err := parseExpression(question.DisplayCondition)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+			continue
+		}
+		errs = append(errs, checkExpressionRefs(field, expr, questions, i)...)
+		for name := range expr.variables {
+			dependency, ok := lastQuestion[name]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s: variable %s is not defined", field, name))
+			} else if dependency >= i {
+				errs = append(errs, fmt.Sprintf("%s: variable %s depends on this or a later question", field, name))
+			}
+		}
+	}
+	return errs
+}
+
+// optionValue 选项的分值，未设置时为选项在列表中的位置（从 1 开始）
+func optionValue(option common.QuestionOption, index int) float64 {
+	if option.OptionValue != nil {
+		return *option.OptionValue
+	}
+	return float64(index + 1)
+}
+
+// questionValueOf 计算题目的作答值，选择题取选中选项的分值之和
+func questionValueOf(question QuestionModel, answer *QuestionResponseModel) questionValue {
+	result := questionValue{}
+	if answer == nil {
+		return result
+	}
+	switch question.Type {
+	case "SingleChoice", "MultiChoice":
+		selected := map[string]bool{}
+		for _, option := range answer.Options {
+			if option.IsSelect {
+				selected[option.OptionID] = true
+			}
+		}
+		for i, option := range question.Options {
+			if selected[option.OptionID] {
+				result.value += optionValue(option, i)
+				result.answered = true
+			}
+		}
+	case "SingleNumFillIn", "MultiNumFillIn":
+		for _, numFillIn := range answer.NumFillIns {
+			result.value += numFillIn.NumContent
+			result.answered = true
+		}
+	case "SingleTextFillIn", "MultiTextFillIn":
+		for _, textFillIn := range answer.TextFillIns {
+			if strings.TrimSpace(textFillIn.TextContent) != "" {
+				result.answered = true
+			}
+		}
+	}
+	return result
+}
+
+// surveyLogic 已解析的计算变量和显示条件，解析失败的表达式为 nil
+type surveyLogic struct {
+	questions  []QuestionModel
+	variables  []VariableModel
+	exprs      []*expression
+	conditions []*expression
+}
+
+// compileSurveyLogic 解析问卷的计算变量和显示条件
+func compileSurveyLogic(questions []QuestionModel, variables []VariableModel) *surveyLogic {
+	logic := &surveyLogic{questions: questions, variables: variables}
+	for _, variable := range variables {
+		expr, _ := parseExpression(variable.Expression)
+		logic.exprs = append(logic.exprs, expr)
+	}
+	for _, question := range questions {
+		var expr *expression
+		if strings.TrimSpace(question.DisplayCondition) != "" {
+			expr, _ = parseExpression(question.DisplayCondition)
+		}
+		logic.conditions = append(logic.conditions, expr)
+	}
+	return logic
+}
+
+// evaluate 按答案计算所有变量，hidden 中的题目视为未作答。无法计算的变量不出现在结果中
+func (l *surveyLogic) evaluate(answers map[string]*QuestionResponseModel, hidden map[string]bool) *exprEnv {
+	env := &exprEnv{variables: map[string]float64{}}
+	for _, question := range l.questions {
+		if hidden[question.QuestionID] {
+			env.questions = append(env.questions, questionValue{})
+			continue
+		}
+		env.questions = append(env.questions, questionValueOf(question, answers[question.QuestionID]))
+	}
+	for i, variable := range l.variables {
+		if l.exprs[i] == nil {
+			continue
+		}
+		if value, err := l.exprs[i].eval(env); err == nil {
+			env.variables[variable.Name] = value
+		}
+	}
+	return env
+}
+
+// applySurveyLogic 按题目顺序计算显示条件，去掉被隐藏题目的答案，返回隐藏的题目和变量值。
+// 显示条件无法计算时题目照常显示
+func applySurveyLogic(questions []QuestionModel, variables []VariableModel, response *ResponseModel) (map[string]bool, map[string]float64) {
+	logic := compileSurveyLogic(questions, variables)
+	answers := map[string]*QuestionResponseModel{}
+	for i := range response.QuestionsResponse {
+		answers[response.QuestionsResponse[i].QID] = &response.QuestionsResponse[i]
+	}
+
+	hidden := map[string]bool{}
+	for i, question := range questions {
+		condition := logic.conditions[i]
+		if condition == nil {
+			continue
+		}
+		value, err := condition.eval(logic.evaluate(answers, hidden))
+		if err == nil && value == 0 {
+			hidden[question.QuestionID] = true
+		}
+	}
+
+	if len(hidden) > 0 {
+		visible := []QuestionResponseModel{}
+		for _, answer := range response.QuestionsResponse {
+			if !hidden[answer.QID] {
+				visible = append(visible, answer)
+			}
+		}
+		response.QuestionsResponse = visible
+		answers = map[string]*QuestionResponseModel{}
+		for i := range response.QuestionsResponse {
+			answers[response.QuestionsResponse[i].QID] = &response.QuestionsResponse[i]
+		}
+	}
+	return hidden, logic.evaluate(answers, hidden).variables
+}
+
+// ParseVariableFilters 解析答卷筛选条件，格式为“变量名 运算符 数字”，如 anxiety>=10
+func ParseVariableFilters(values []string) ([]VariableFilter, error) {
+	filters := []VariableFilter{}
+	for _, value := range values {
+		match := variableFilterPattern.FindStringSubmatch(value)
+		if match == nil {
+			return nil, fmt.Errorf("invalid filter %q", value)
+		}
+		number, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q", value)
+		}
+		filters = append(filters, VariableFilter{Name: match[1], Operator: match[2], Value: number})
+	}
+	return filters, nil
+}
+
+// applyVariableFilters 为答卷查询加上计算变量筛选条件，没有该变量值的答卷不满足条件
+func applyVariableFilters(query *gorm.DB, surveyID string, filters []VariableFilter) *gorm.DB {
+	for _, filter := range filters {
+		operator, ok := filterOperators[filter.Operator]
+		if !ok {
+			continue
+		}
+		subQuery := common.DB.Model(&common.ResponseVariable{}).
+			Select("ResponseID").
+			Where("SurveyID = ? AND Name = ? AND Value "+operator+" ?", surveyID, filter.Name, filter.Value)
+		query = query.Where("ResponseID IN (?)", subQuery)
+	}
+	return query
+}
+
+// loadResponseVariables 读取多份答卷的变量值，按答卷 ID 分组
+func loadResponseVariables(surveyID string, responseIDs []string) (map[string]map[string]float64, error) {
+	result := map[string]map[string]float64{}
+	if len(responseIDs) == 0 {
+		return result, nil
+	}
+	var rows []common.ResponseVariable
+	if err := common.DB.Where("SurveyID = ? AND ResponseID IN ?", surveyID, responseIDs).Find(&rows).Error; err != nil {
+		return nil, errors.New("failed to retrieve response variables")
+	}
+	for _, row := range rows {
+		if result[row.ResponseID] == nil {
+			result[row.ResponseID] = map[string]float64{}
+		}
+		result[row.ResponseID][row.Name] = row.Value
+	}
+	return result, nil
+}
+
+// GetVariableStats 统计已发布变量在有效答卷中的分布，可按变量筛选答卷
+func GetVariableStats(surveyID, userID string, filters []VariableFilter) ([]VariableStats, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := common.DB.Model(&common.SurveyResponse{}).
+		Select("ResponseID").
+		Where("SurveyID = ? AND IsInvalid = ?", surveyID, false)
+	responses = applyVariableFilters(responses, surveyID, filters)
+
+	var rows []common.ResponseVariable
+	if err := common.DB.Where("SurveyID = ? AND ResponseID IN (?)", surveyID, responses).Find(&rows).Error; err != nil {
+		return nil, errors.New("failed to retrieve response variables")
+	}
+	values := map[string][]float64{}
+	for _, row := range rows {
+		values[row.Name] = append(values[row.Name], row.Value)
+	}
+
+	stats := []VariableStats{}
+	for _, variable := range decodeVariables(survey.Variables) {
+		item := VariableStats{Name: variable.Name, Expression: variable.Expression}
+		list := values[variable.Name]
+		if len(list) > 0 {
+			sort.Float64s(list)
+			sum := 0.0
+			for _, value := range list {
+				sum += value
+			}
+			item.Count = len(list)
+			item.Mean = sum / float64(len(list))
+			item.Min = list[0]
+			item.Max = list[len(list)-1]
+			if len(list)%2 == 1 {
+				item.Median = list[len(list)/2]
+			} else {
+				item.Median = (list[len(list)/2-1] + list[len(list)/2]) / 2
+			}
+			item.Mean = math.Round(item.Mean*10000) / 10000
+		}
+		stats = append(stats, item)
+	}
+	return stats, nil
+}
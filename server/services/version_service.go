@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"server/common"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SurveyVersionSummary 版本列表项
+type SurveyVersionSummary struct {
+	Version    int       `json:"version"`
+	UserID     string    `json:"userId"`
+	UserName   string    `json:"userName"`
+	Note       string    `json:"note"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+// SurveyVersionDetail 版本详情
+type SurveyVersionDetail struct {
+	SurveyVersionSummary
+	Survey SurveyModel `json:"survey"`
+}
+
+// QuestionDiff 单个题目在两个版本之间的差异
+type QuestionDiff struct {
+	QuestionID string   `json:"questionId"`
+	Title      string   `json:"title"`
+	Change     string   `json:"change"` // added / removed / modified / unchanged
+	Fields     []string `json:"fields"` // 发生变化的字段
+	FromIndex  int      `json:"fromIndex"`
+	ToIndex    int      `json:"toIndex"`
+}
+
+// SurveyVersionDiff 两个版本之间的差异
+type SurveyVersionDiff struct {
+	From         int            `json:"from"`
+	To           int            `json:"to"`
+	TitleChanged bool           `json:"titleChanged"`
+	Questions    []QuestionDiff `json:"questions"`
+}
+
+// createSurveyVersion 将 SurveyModel JSON 保存为版本快照
+func createSurveyVersion(tx *gorm.DB, survey *common.Survey, snapshot, userID, note string) error {
+	version := common.SurveyVersion{
+		SurveyID:   survey.SurveyID,
+		Version:    survey.CurrentVersion,
+		Snapshot:   snapshot,
+		UserID:     userID,
+		Note:       note,
+		CreateTime: time.Now(),
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return errors.New("failed to save survey version")
+	}
+	return nil
+}
+
+// ListSurveyVersions 获取问卷的版本列表，按版本号倒序
+func ListSurveyVersions(surveyID, userID string) ([]SurveyVersionSummary, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
+	}
+
+	versions := []SurveyVersionSummary{}
+	err := common.DB.Table("survey_versions AS v").
+		Select("v.Version AS version, v.UserID AS user_id, u.UserName AS user_name, v.Note AS note, v.CreateTime AS create_time").
+		Joins("LEFT JOIN users AS u ON u.UserID = v.UserID").
+		Where("v.SurveyID = ?", surveyID).
+		Order("v.Version DESC").
+		Scan(&versions).Error
+	if err != nil {
+		return nil, errors.New("failed to retrieve versions")
+	}
+	return versions, nil
+}
+
+// loadSurveyVersion 读取并解析指定版本的快照
+func loadSurveyVersion(surveyID string, versionNumber int) (*common.SurveyVersion, *SurveyModel, error) {
+	var version common.SurveyVersion
+	if err := common.DB.Where("SurveyID = ? AND Version = ?", surveyID, versionNumber).First(&version).Error; err != nil {
+		return nil, nil, errors.New("version not found")
+	}
+	var model SurveyModel
+	if err := json.Unmarshal([]byte(version.Snapshot), &model); err != nil {
+		return nil, nil, errors.New("failed to decode survey snapshot")
+	}
+	return &version, &model, nil
+}
+
+// GetSurveyVersion 获取指定版本的问卷内容
+func GetSurveyVersion(surveyID, userID string, versionNumber int) (*SurveyVersionDetail, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
+	}
+	version, model, err := loadSurveyVersion(surveyID, versionNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var user common.User
+	common.DB.Where("UserID = ?", version.UserID).First(&user)
+
+	return &SurveyVersionDetail{
+		SurveyVersionSummary: SurveyVersionSummary{
+			Version:    version.Version,
+			UserID:     version.UserID,
+			UserName:   user.UserName,
+			Note:       version.Note,
+			CreateTime: version.CreateTime,
+		},
+		Survey: *model,
+	}, nil
+}
+
+// DiffSurveyVersions 按题目比较两个版本
+func DiffSurveyVersions(surveyID, userID string, from, to int) (*SurveyVersionDiff, error) {
+	if _, err := getOwnedSurvey(surveyID, userID); err != nil {
+		return nil, err
+	}
+	_, fromModel, err := loadSurveyVersion(surveyID, from)
+	if err != nil {
+		return nil, err
+	}
+	_, toModel, err := loadSurveyVersion(surveyID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SurveyVersionDiff{
+		From:         from,
+		To:           to,
+		TitleChanged: fromModel.Title != toModel.Title,
+		Questions:    []QuestionDiff{},
+	}
+
+	fromIndex := map[string]int{}
+	for i, question := range fromModel.Questions {
+		fromIndex[question.QuestionID] = i
+	}
+
+	// 按新版本顺序列出新增、修改和未变化的题目
+	seen := map[string]bool{}
+	for i, question := range toModel.Questions {
+		seen[question.QuestionID] = true
+		j, ok := fromIndex[question.QuestionID]
+		if !ok {
+			diff.Questions = append(diff.Questions, QuestionDiff{
+				QuestionID: question.QuestionID,
+				Title:      question.Title,
+				Change:     "added",
+				Fields:     []string{},
+				FromIndex:  -1,
+				ToIndex:    i,
+			})
+			continue
+		}
+
+		fields := diffQuestionFields(fromModel.Questions[j], question)
+		change := "unchanged"
+		if len(fields) > 0 || i != j {
+			change = "modified"
+		}
+		if i != j {
+			fields = append(fields, "Position")
+		}
+		diff.Questions = append(diff.Questions, QuestionDiff{
+			QuestionID: question.QuestionID,
+			Title:      question.Title,
+			Change:     change,
+			Fields:     fields,
+			FromIndex:  j,
+			ToIndex:    i,
+		})
+	}
+
+	// 列出被删除的题目
+	for j, question := range fromModel.Questions {
+		if seen[question.QuestionID] {
+			continue
+		}
+		diff.Questions = append(diff.Questions, QuestionDiff{
+			QuestionID: question.QuestionID,
+			Title:      question.Title,
+			Change:     "removed",
+			Fields:     []string{},
+			FromIndex:  j,
+			ToIndex:    -1,
+		})
+	}
+
+	return diff, nil
+}
+
+// diffQuestionFields 比较同一题目的两个版本，返回发生变化的字段名
+func diffQuestionFields(a, b QuestionModel) []string {
+	fields := []string{}
+	if a.Type != b.Type {
+		fields = append(fields, "QuestionType")
+	}
+	if a.Title != b.Title {
+		fields = append(fields, "Title")
+	}
+	if a.Description != b.Description {
+		fields = append(fields, "Description")
+	}
+	if a.Image != b.Image {
+		fields = append(fields, "Image")
+	}
+	if a.Required != b.Required {
+		fields = append(fields, "Required")
+	}
+	if a.RandomizeOptions != b.RandomizeOptions || a.PageBreak != b.PageBreak || a.DisplayCondition != b.DisplayCondition {
+		fields = append(fields, "Display")
+	}
+	if a.LeastChoice != b.LeastChoice || a.MaxChoice != b.MaxChoice {
+		fields = append(fields, "Choice")
+	}
+	if a.Points != b.Points || a.PartialCredit != b.PartialCredit {
+		fields = append(fields, "Scoring")
+	}
+
+	optionsA, _ := json.Marshal(a.Options)
+	optionsB, _ := json.Marshal(b.Options)
+	if string(optionsA) != string(optionsB) {
+		fields = append(fields, "Options")
+	}
+	textA, _ := json.Marshal(a.TextFillIns)
+	textB, _ := json.Marshal(b.TextFillIns)
+	if string(textA) != string(textB) {
+		fields = append(fields, "TextFillIns")
+	}
+	numA, _ := json.Marshal(a.NumFillIns)
+	numB, _ := json.Marshal(b.NumFillIns)
+	if string(numA) != string(numB) {
+		fields = append(fields, "NumFillIns")
+	}
+	return fields
+}
+
+// RollbackSurveyVersion 将问卷草稿恢复到指定版本，恢复操作本身会生成一个新版本，发布后答题者可见。
+// expectedRevision 与当前修订号不一致时返回 ErrRevisionConflict，返回新版本号和新修订号
+func RollbackSurveyVersion(surveyID, userID string, versionNumber, expectedRevision int) (int, int, error) {
+	survey, err := getOwnedSurvey(surveyID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, model, err := loadSurveyVersion(surveyID, versionNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var newVersion int
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		newVersion, err = saveSurveyContent(tx, survey, model, userID, "rollback to version "+strconv.Itoa(versionNumber), expectedRevision)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return newVersion, survey.Revision, nil
+}
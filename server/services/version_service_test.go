@@ -0,0 +1,67 @@
+package services
+
+import (
+	"reflect"
+	"server/common"
+	"testing"
+)
+
+func TestDiffQuestionFields(t *testing.T) {
+	base := QuestionModel{
+		QuestionID:  "q1",
+		Type:        "SingleChoice",
+		Title:       "您的年龄段是？",
+		Description: "请选择一项",
+		Required:    true,
+		LeastChoice: 1,
+		MaxChoice:   1,
+		Options: []common.QuestionOption{
+			{OptionID: "a", OptionContent: "18 岁以下"},
+			{OptionID: "b", OptionContent: "18 岁及以上"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		change func(q *QuestionModel)
+		want   []string
+	}{
+		{"unchanged", func(q *QuestionModel) {}, []string{}},
+		{"type", func(q *QuestionModel) { q.Type = "MultiChoice" }, []string{"QuestionType"}},
+		{"title", func(q *QuestionModel) { q.Title = "年龄" }, []string{"Title"}},
+		{"description", func(q *QuestionModel) { q.Description = "" }, []string{"Description"}},
+		{"image", func(q *QuestionModel) { q.Image = "images/a.png" }, []string{"Image"}},
+		{"required", func(q *QuestionModel) { q.Required = false }, []string{"Required"}},
+		{"randomize options", func(q *QuestionModel) { q.RandomizeOptions = true }, []string{"Display"}},
+		{"page break", func(q *QuestionModel) { q.PageBreak = true }, []string{"Display"}},
+		{"display condition", func(q *QuestionModel) { q.DisplayCondition = "Q1 > 0" }, []string{"Display"}},
+		{"choice range", func(q *QuestionModel) { q.MaxChoice = 2 }, []string{"Choice"}},
+		{"points", func(q *QuestionModel) { q.Points = 5 }, []string{"Scoring"}},
+		{"partial credit", func(q *QuestionModel) { q.PartialCredit = true }, []string{"Scoring"}},
+		{"option content", func(q *QuestionModel) {
+			q.Options = []common.QuestionOption{{OptionID: "a", OptionContent: "18 岁以下"}, {OptionID: "b", OptionContent: "成年"}}
+		}, []string{"Options"}},
+		{"option order", func(q *QuestionModel) {
+			q.Options = []common.QuestionOption{q.Options[1], q.Options[0]}
+		}, []string{"Options"}},
+		{"text fill-ins", func(q *QuestionModel) {
+			q.TextFillIns = []common.QuestionTextFillIn{{TextFillInID: "t1"}}
+		}, []string{"TextFillIns"}},
+		{"number fill-ins", func(q *QuestionModel) {
+			q.NumFillIns = []common.QuestionNumFillIn{{NumFillInID: "n1"}}
+		}, []string{"NumFillIns"}},
+		{"several fields", func(q *QuestionModel) {
+			q.Title = "年龄"
+			q.Required = false
+			q.Points = 1
+		}, []string{"Title", "Required", "Scoring"}},
+	}
+	for _, tt := range tests {
+		changed := base
+		changed.Options = append([]common.QuestionOption{}, base.Options...)
+		tt.change(&changed)
+		if got := diffQuestionFields(base, changed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diffQuestionFields = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}